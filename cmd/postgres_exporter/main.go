@@ -53,6 +53,14 @@ var (
 	collectionTimeout     = kingpin.Flag("collection-timeout", "Timeout for collecting the statistics when the database is slow").Default("1m").Envar("PG_EXPORTER_COLLECTION_TIMEOUT").String()
 	collectorFlags        = newCollectorFlags()
 	statStatementsFlags   = newPGStatStatementsFlags()
+	userFunctionsFlags    = newPGStatUserFunctionsFlags()
+	preparedXactsFlags    = newPreparedXactsFlags()
+	sequencesFlags        = newSequencesFlags()
+	settingsFlags         = newSettingsFlags()
+	bloatFlags            = newBloatFlags()
+	indexHealthFlags      = newIndexHealthFlags()
+	wraparoundFlags       = newTableWraparoundFlags()
+	tablespaceFlags       = newTablespaceFlags()
 	logger                = promslog.NewNopLogger()
 )
 
@@ -69,6 +77,51 @@ type pgStatStatementsFlags struct {
 	excludeUsers     *string
 }
 
+type pgStatUserFunctionsFlags struct {
+	includeSchemas   *string
+	excludeSchemas   *string
+	includeFunctions *string
+	excludeFunctions *string
+	limit            *uint
+}
+
+type pgPreparedXactsFlags struct {
+	includeGID *bool
+	gidLimit   *uint
+}
+
+type pgSequencesFlags struct {
+	threshold *float64
+	limit     *uint
+}
+
+type pgSettingsFlags struct {
+	audit       *bool
+	info        *string
+	redact      *string
+	hash        *bool
+	hashExclude *string
+}
+
+type pgBloatFlags struct {
+	mode     *string
+	limit    *uint
+	cacheTTL *time.Duration
+}
+
+type pgIndexHealthFlags struct {
+	includeIndexes *bool
+	indexLimit     *uint
+}
+
+type pgTableWraparoundFlags struct {
+	limit *uint
+}
+
+type pgTablespaceFlags struct {
+	perDatabase *bool
+}
+
 func newCollectorFlags() collectorFlagSet {
 	defaults := config.DefaultCollectorConfig()
 	names := make([]string, 0, len(defaults))
@@ -116,6 +169,130 @@ func newPGStatStatementsFlags() pgStatStatementsFlags {
 	}
 }
 
+func newPGStatUserFunctionsFlags() pgStatUserFunctionsFlags {
+	return pgStatUserFunctionsFlags{
+		includeSchemas: kingpin.Flag(
+			"collector.stat_user_functions.include_schemas",
+			"Comma-separated list of schemas to include. (default: all)",
+		).Default("").String(),
+		excludeSchemas: kingpin.Flag(
+			"collector.stat_user_functions.exclude_schemas",
+			"Comma-separated list of schemas to exclude. (default: none)",
+		).Default("").String(),
+		includeFunctions: kingpin.Flag(
+			"collector.stat_user_functions.include_functions",
+			"Comma-separated list of function names to include. (default: all)",
+		).Default("").String(),
+		excludeFunctions: kingpin.Flag(
+			"collector.stat_user_functions.exclude_functions",
+			"Comma-separated list of function names to exclude. (default: none)",
+		).Default("").String(),
+		limit: kingpin.Flag(
+			"collector.stat_user_functions.limit",
+			"Maximum number of functions to return, by total time.",
+		).Default(fmt.Sprintf("%d", config.DefaultPGStatUserFunctionsLimit)).Uint(),
+	}
+}
+
+func newPreparedXactsFlags() pgPreparedXactsFlags {
+	return pgPreparedXactsFlags{
+		includeGID: kingpin.Flag(
+			"collector.prepared_xacts.include_gid",
+			"Report the oldest prepared transactions with their gid. (default: disabled)",
+		).Default(strconv.FormatBool(config.DefaultPreparedXactsIncludeGID)).Bool(),
+		gidLimit: kingpin.Flag(
+			"collector.prepared_xacts.gid_limit",
+			"Maximum number of prepared transactions to report with their gid.",
+		).Default(fmt.Sprintf("%d", config.DefaultPreparedXactsGIDLimit)).Uint(),
+	}
+}
+
+func newSequencesFlags() pgSequencesFlags {
+	return pgSequencesFlags{
+		threshold: kingpin.Flag(
+			"collector.sequences.threshold",
+			"Fraction of its range a sequence must have used to be reported individually, between 0 and 1.",
+		).Default(strconv.FormatFloat(config.DefaultSequencesThreshold, 'f', -1, 64)).Float64(),
+		limit: kingpin.Flag(
+			"collector.sequences.limit",
+			"Maximum number of sequences to return per database, closest to exhaustion first.",
+		).Default(fmt.Sprintf("%d", config.DefaultSequencesLimit)).Uint(),
+	}
+}
+
+func newSettingsFlags() pgSettingsFlags {
+	return pgSettingsFlags{
+		audit: kingpin.Flag(
+			"collector.settings.audit",
+			"Report the string and enum settings selected by collector.settings.info as pg_settings_info, and with collector.settings.hash a hash of the configuration as pg_settings_hash_info. (default: disabled)",
+		).Default(strconv.FormatBool(config.DefaultSettingsAudit)).Bool(),
+		info: kingpin.Flag(
+			"collector.settings.info",
+			"Comma-separated list of patterns of string and enum settings to report as pg_settings_info. Use * for all.",
+		).Default(strings.Join(config.DefaultSettingsInfo, ",")).String(),
+		redact: kingpin.Flag(
+			"collector.settings.redact",
+			"Comma-separated list of patterns of settings whose value is redacted in pg_settings_info.",
+		).Default(strings.Join(config.DefaultSettingsRedact, ",")).String(),
+		hash: kingpin.Flag(
+			"collector.settings.hash",
+			"Report a hash of the effective configuration as pg_settings_hash_info, requires collector.settings.audit. (default: disabled)",
+		).Default(strconv.FormatBool(config.DefaultSettingsHash)).Bool(),
+		hashExclude: kingpin.Flag(
+			"collector.settings.hash_exclude",
+			"Comma-separated list of patterns of settings to leave out of the configuration hash.",
+		).Default(strings.Join(config.DefaultSettingsHashExclude, ",")).String(),
+	}
+}
+
+func newBloatFlags() pgBloatFlags {
+	return pgBloatFlags{
+		mode: kingpin.Flag(
+			"collector.bloat.mode",
+			"How to measure bloat: estimate from the planner statistics, or exact with the pgstattuple extension.",
+		).Default(config.DefaultBloatMode).Enum(config.BloatModeEstimate, config.BloatModeExact),
+		limit: kingpin.Flag(
+			"collector.bloat.limit",
+			"Maximum number of tables and of indexes to return per database, most bloated first.",
+		).Default(fmt.Sprintf("%d", config.DefaultBloatLimit)).Uint(),
+		cacheTTL: kingpin.Flag(
+			"collector.bloat.cache_ttl",
+			"How long to reuse the bloat measurements before measuring again. (default: measure on every scrape)",
+		).Default(config.DefaultBloatCacheTTL.String()).Duration(),
+	}
+}
+
+func newIndexHealthFlags() pgIndexHealthFlags {
+	return pgIndexHealthFlags{
+		includeIndexes: kingpin.Flag(
+			"collector.index_health.include_indexes",
+			"Report the largest indexes and foreign keys with each problem individually. (default: disabled)",
+		).Default(strconv.FormatBool(config.DefaultIndexHealthIncludeIndexes)).Bool(),
+		indexLimit: kingpin.Flag(
+			"collector.index_health.index_limit",
+			"Maximum number of indexes or foreign keys to report individually per problem and database.",
+		).Default(fmt.Sprintf("%d", config.DefaultIndexHealthIndexLimit)).Uint(),
+	}
+}
+
+func newTableWraparoundFlags() pgTableWraparoundFlags {
+	return pgTableWraparoundFlags{
+		limit: kingpin.Flag(
+			"collector.table_wraparound.limit",
+			"Maximum number of relations to return per database, closest to a forced anti-wraparound vacuum first.",
+		).Default(fmt.Sprintf("%d", config.DefaultTableWraparoundLimit)).Uint(),
+	}
+}
+
+func newTablespaceFlags() pgTablespaceFlags {
+	return pgTablespaceFlags{
+		perDatabase: kingpin.Flag(
+			"collector.tablespace.per_database",
+			"Break down the size of the connected database per tablespace. (default: disabled)",
+		).Default(strconv.FormatBool(config.DefaultTablespacePerDatabase)).Bool(),
+	}
+}
+
 func main() {
 	kingpin.Version(version.Print(exporterName))
 	promslogConfig := &promslog.Config{}
@@ -231,6 +408,7 @@ func buildConfig(dsns []string) (config.Config, error) {
 
 	cfg := config.NewConfigWithDefaults()
 	cfg.DataSourceNames = dsns
+	cfg.CredentialFiles = exporter.GetDataSourceCredentialFiles()
 	cfg.MetricPrefix = *metricPrefix
 	cfg.CollectionTimeout = parsedCollectionTimeout
 	cfg.DisableDefaultMetrics = *disableDefaultMetrics
@@ -247,6 +425,43 @@ func buildConfig(dsns []string) (config.Config, error) {
 		ExcludeDatabases: splitList(*statStatementsFlags.excludeDatabases),
 		ExcludeUsers:     splitList(*statStatementsFlags.excludeUsers),
 	}
+	cfg.PGStatUserFunctions = config.PGStatUserFunctionsConfig{
+		IncludeSchemas:   splitList(*userFunctionsFlags.includeSchemas),
+		ExcludeSchemas:   splitList(*userFunctionsFlags.excludeSchemas),
+		IncludeFunctions: splitList(*userFunctionsFlags.includeFunctions),
+		ExcludeFunctions: splitList(*userFunctionsFlags.excludeFunctions),
+		Limit:            *userFunctionsFlags.limit,
+	}
+	cfg.PreparedXacts = config.PreparedXactsConfig{
+		IncludeGID: *preparedXactsFlags.includeGID,
+		GIDLimit:   *preparedXactsFlags.gidLimit,
+	}
+	cfg.Sequences = config.SequencesConfig{
+		Threshold: *sequencesFlags.threshold,
+		Limit:     *sequencesFlags.limit,
+	}
+	cfg.Settings = config.SettingsConfig{
+		Audit:       *settingsFlags.audit,
+		Info:        splitList(*settingsFlags.info),
+		Redact:      splitList(*settingsFlags.redact),
+		Hash:        *settingsFlags.hash,
+		HashExclude: splitList(*settingsFlags.hashExclude),
+	}
+	cfg.Bloat = config.BloatConfig{
+		Mode:     *bloatFlags.mode,
+		Limit:    *bloatFlags.limit,
+		CacheTTL: *bloatFlags.cacheTTL,
+	}
+	cfg.IndexHealth = config.IndexHealthConfig{
+		IncludeIndexes: *indexHealthFlags.includeIndexes,
+		IndexLimit:     *indexHealthFlags.indexLimit,
+	}
+	cfg.TableWraparound = config.TableWraparoundConfig{
+		Limit: *wraparoundFlags.limit,
+	}
+	cfg.Tablespace = config.TablespaceConfig{
+		PerDatabase: *tablespaceFlags.perDatabase,
+	}
 	return cfg, nil
 }
 
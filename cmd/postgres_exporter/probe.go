@@ -45,7 +45,8 @@ func handleProbe(logger *slog.Logger, authHandler *config.Handler, baseConfig co
 				http.Error(w, fmt.Sprintf("auth_module %s not found", authModuleName), http.StatusBadRequest)
 				return
 			}
-			if authModule.UserPass.Username == "" || authModule.UserPass.Password == "" {
+			userPass := authModule.UserPass
+			if authModule.Type == config.AuthModuleTypeUserPass && ((userPass.Username == "" && userPass.UsernameFile == "") || (userPass.Password == "" && userPass.PasswordFile == "")) {
 				http.Error(w, fmt.Sprintf("auth_module %s has no username or password", authModuleName), http.StatusBadRequest)
 				return
 			}
@@ -65,6 +66,10 @@ func handleProbe(logger *slog.Logger, authHandler *config.Handler, baseConfig co
 		// Copy process-level config before setting the per-request target DSN.
 		probeConfig := baseConfig
 		probeConfig.DataSourceNames = []string{dsn.GetConnectionString()}
+		probeConfig.CredentialFiles = config.CredentialFiles{}
+		if authModule.Type == config.AuthModuleTypeUserPass {
+			probeConfig.CredentialFiles = authModule.UserPass.CredentialFiles()
+		}
 		validatedConfig, err := probeConfig.Validate()
 		if err != nil {
 			logger.Error("invalid probe config", "err", err)
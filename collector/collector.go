@@ -54,6 +54,14 @@ type collectorConfig struct {
 	logger                 *slog.Logger
 	excludeDatabases       []string
 	pgStatStatementsConfig config.PGStatStatementsConfig
+	pgStatUserFunctions    config.PGStatUserFunctionsConfig
+	preparedXacts          config.PreparedXactsConfig
+	sequences              config.SequencesConfig
+	settings               config.SettingsConfig
+	bloat                  config.BloatConfig
+	indexHealth            config.IndexHealthConfig
+	tableWraparound        config.TableWraparoundConfig
+	tablespace             config.TablespaceConfig
 }
 
 func registerCollector(name string, createFunc func(collectorConfig) (Collector, error)) {
@@ -68,10 +76,19 @@ type PostgresCollector struct {
 	Collectors map[string]Collector
 	logger     *slog.Logger
 
-	instance          *instance
-	CollectionTimeout time.Duration
-	collectorStates   map[string]bool
-	pgStatStatements  config.PGStatStatementsConfig
+	instance            *instance
+	CollectionTimeout   time.Duration
+	collectorStates     map[string]bool
+	pgStatStatements    config.PGStatStatementsConfig
+	pgStatUserFunctions config.PGStatUserFunctionsConfig
+	preparedXacts       config.PreparedXactsConfig
+	sequences           config.SequencesConfig
+	settings            config.SettingsConfig
+	bloat               config.BloatConfig
+	indexHealth         config.IndexHealthConfig
+	tableWraparound     config.TableWraparoundConfig
+	tablespace          config.TablespaceConfig
+	credentialFiles     config.CredentialFiles
 }
 
 type Option func(*PostgresCollector) error
@@ -79,10 +96,17 @@ type Option func(*PostgresCollector) error
 // NewPostgresCollector creates a new PostgresCollector.
 func NewPostgresCollector(logger *slog.Logger, excludeDatabases []string, dsn string, filters []string, options ...Option) (*PostgresCollector, error) {
 	p := &PostgresCollector{
-		logger:            logger,
-		collectorStates:   config.DefaultCollectorConfig(),
-		pgStatStatements:  defaultPGStatStatementsConfig(),
-		CollectionTimeout: time.Minute,
+		logger:              logger,
+		collectorStates:     config.DefaultCollectorConfig(),
+		pgStatStatements:    defaultPGStatStatementsConfig(),
+		pgStatUserFunctions: defaultPGStatUserFunctionsConfig(),
+		preparedXacts:       defaultPreparedXactsConfig(),
+		sequences:           defaultSequencesConfig(),
+		settings:            defaultSettingsConfig(),
+		bloat:               defaultBloatConfig(),
+		indexHealth:         defaultIndexHealthConfig(),
+		tableWraparound:     defaultTableWraparoundConfig(),
+		CollectionTimeout:   time.Minute,
 	}
 	// Apply options to customize the collector
 	for _, o := range options {
@@ -116,6 +140,14 @@ func NewPostgresCollector(logger *slog.Logger, excludeDatabases []string, dsn st
 			logger:                 logger.With("collector", key),
 			excludeDatabases:       excludeDatabases,
 			pgStatStatementsConfig: p.pgStatStatements,
+			pgStatUserFunctions:    p.pgStatUserFunctions,
+			preparedXacts:          p.preparedXacts,
+			sequences:              p.sequences,
+			settings:               p.settings,
+			bloat:                  p.bloat,
+			indexHealth:            p.indexHealth,
+			tableWraparound:        p.tableWraparound,
+			tablespace:             p.tablespace,
 		})
 		if err != nil {
 			return nil, err
@@ -129,10 +161,11 @@ func NewPostgresCollector(logger *slog.Logger, excludeDatabases []string, dsn st
 		return nil, errors.New("empty dsn")
 	}
 
-	instance, err := newInstance(dsn)
+	instance, err := newInstance(dsn, p.credentialFiles)
 	if err != nil {
 		return nil, err
 	}
+	_, instance.observeTLS = collectors[serverCertificateSubsystem]
 	p.instance = instance
 
 	return p, nil
@@ -159,6 +192,71 @@ func WithPGStatStatementsConfig(cfg config.PGStatStatementsConfig) Option {
 	}
 }
 
+func WithPGStatUserFunctionsConfig(cfg config.PGStatUserFunctionsConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.pgStatUserFunctions = withPGStatUserFunctionsDefaults(cfg)
+		return nil
+	}
+}
+
+func WithPreparedXactsConfig(cfg config.PreparedXactsConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.preparedXacts = withPreparedXactsDefaults(cfg)
+		return nil
+	}
+}
+
+func WithSequencesConfig(cfg config.SequencesConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.sequences = withSequencesDefaults(cfg)
+		return nil
+	}
+}
+
+func WithSettingsConfig(cfg config.SettingsConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.settings = cfg
+		return nil
+	}
+}
+
+func WithBloatConfig(cfg config.BloatConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.bloat = withBloatDefaults(cfg)
+		return nil
+	}
+}
+
+func WithIndexHealthConfig(cfg config.IndexHealthConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.indexHealth = withIndexHealthDefaults(cfg)
+		return nil
+	}
+}
+
+func WithTableWraparoundConfig(cfg config.TableWraparoundConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.tableWraparound = withTableWraparoundDefaults(cfg)
+		return nil
+	}
+}
+
+func WithTablespaceConfig(cfg config.TablespaceConfig) Option {
+	return func(e *PostgresCollector) error {
+		e.tablespace = cfg
+		return nil
+	}
+}
+
+// WithCredentialFiles reads the username and password from files on every
+// new connection.
+func WithCredentialFiles(files config.CredentialFiles) Option {
+	return func(e *PostgresCollector) error {
+		e.credentialFiles = files
+		return nil
+	}
+}
+
 func WithCollectionTimeout(s string) Option {
 	return func(e *PostgresCollector) error {
 		duration, err := time.ParseDuration(s)
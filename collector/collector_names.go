@@ -16,16 +16,25 @@ package collector
 import "github.com/prometheus-community/postgres_exporter/config"
 
 const (
+	autovacuumBacklogSubsystem       = config.CollectorAutovacuumBacklog
+	bloatSubsystem                   = config.CollectorBloat
 	buffercacheSummarySubsystem      = config.CollectorBuffercacheSummary
+	configFilesSubsystem             = config.CollectorConfigFiles
+	connectionEncryptionSubsystem    = config.CollectorConnectionEncryption
+	controlFileSubsystem             = config.CollectorControlFile
 	databaseSubsystem                = config.CollectorDatabase
 	databaseWraparoundSubsystem      = config.CollectorDatabaseWraparound
+	indexHealthSubsystem             = config.CollectorIndexHealth
 	locksSubsystem                   = config.CollectorLocks
 	longRunningTransactionsSubsystem = config.CollectorLongRunningTransactions
 	postmasterSubsystem              = config.CollectorPostmaster
+	preparedXactsSubsystem           = config.CollectorPreparedXacts
 	processIdleSubsystem             = config.CollectorProcessIdle
 	replicationSubsystem             = config.CollectorReplication
 	replicationSlotsSubsystem        = config.CollectorReplicationSlots
 	rolesSubsystem                   = config.CollectorRoles
+	sequencesSubsystem               = config.CollectorSequences
+	serverCertificateSubsystem       = config.CollectorServerCertificate
 	settingsSubsystem                = config.CollectorSettings
 	statActivitySubsystem            = config.CollectorStatActivity
 	statActivityAutovacuumSubsystem  = config.CollectorStatActivityAutovacuum
@@ -33,13 +42,27 @@ const (
 	bgWriterSubsystem                = config.CollectorStatBGWriter
 	statCheckpointerSubsystem        = config.CollectorStatCheckpointer
 	statDatabaseSubsystem            = config.CollectorStatDatabase
+	statIOSubsystem                  = config.CollectorStatIO
+	progressAnalyzeSubsystem         = config.CollectorStatProgressAnalyze
+	progressBasebackupSubsystem      = config.CollectorStatProgressBasebackup
+	progressClusterSubsystem         = config.CollectorStatProgressCluster
+	progressCopySubsystem            = config.CollectorStatProgressCopy
+	progressCreateIndexSubsystem     = config.CollectorStatProgressCreateIndex
 	progressVacuumSubsystem          = config.CollectorStatProgressVacuum
 	statReplicationSubsystem         = config.CollectorStatReplication
+	statReplicationSlotsSubsystem    = config.CollectorStatReplicationSlots
 	statStatementsSubsystem          = config.CollectorStatStatements
+	statSubscriptionSubsystem        = config.CollectorStatSubscription
+	statUserFunctionsSubsystem       = config.CollectorStatUserFunctions
 	userTableSubsystem               = config.CollectorStatUserTables
+	statWALSubsystem                 = config.CollectorStatWAL
 	statWalReceiverSubsystem         = config.CollectorStatWalReceiver
 	statioUserIndexesSubsystem       = config.CollectorStatioUserIndexes
 	statioUserTableSubsystem         = config.CollectorStatioUserTables
+	tableWraparoundSubsystem         = config.CollectorTableWraparound
+	tablespaceSubsystem              = config.CollectorTablespace
+	tempFilesSubsystem               = config.CollectorTempFiles
 	walSubsystem                     = config.CollectorWal
+	walArchiveSubsystem              = config.CollectorWalArchive
 	xlogLocationSubsystem            = config.CollectorXlogLocation
 )
@@ -13,6 +13,7 @@
 package collector
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -22,6 +23,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/promslog"
+	"github.com/smartystreets/goconvey/convey"
 )
 
 type labelMap map[string]string
@@ -66,6 +68,45 @@ func sanitizeQuery(q string) string {
 	return q
 }
 
+// testCollectorUpdate runs one Update of c against inst, backed by a mock
+// database on which expectQueries, if not nil, sets up the expected queries.
+// It checks that exactly the expected metrics are emitted, in order, and that
+// every expected query ran.
+func testCollectorUpdate(t *testing.T, c Collector, inst *instance, expectQueries func(sqlmock.Sqlmock), expected []MetricResult) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening a stub db connection: %s", err)
+	}
+	defer db.Close()
+
+	inst.db = db
+	if expectQueries != nil {
+		expectQueries(mock)
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+
+		if err := c.Update(context.Background(), inst, ch); err != nil {
+			t.Errorf("Error calling %T.Update: %s", c, err)
+		}
+	}()
+
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range expected {
+			m := readMetric(<-ch)
+			convey.So(m, convey.ShouldResemble, expect)
+		}
+		_, more := <-ch
+		convey.So(more, convey.ShouldBeFalse)
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled exceptions: %s", err)
+	}
+}
+
 // We ensure that when the database respond after a long time
 // The collection process still occurs in a predictable manner
 // Will avoid accumulation of queries on a completely frozen DB
@@ -236,3 +277,169 @@ func TestNewPGStatStatementsCollectorUsesConfig(t *testing.T) {
 		t.Fatalf("excludedUsers[0] = %q, want monitor", got.excludedUsers[0])
 	}
 }
+
+func TestNewPGStatUserFunctionsCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGStatUserFunctionsCollector(collectorConfig{
+		logger: promslog.NewNopLogger(),
+		pgStatUserFunctions: config.PGStatUserFunctionsConfig{
+			IncludeSchemas:   []string{"billing"},
+			ExcludeFunctions: []string{"refund"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPGStatUserFunctionsCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGStatUserFunctionsCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGStatUserFunctionsCollector", collector)
+	}
+	if got.limit != config.DefaultPGStatUserFunctionsLimit {
+		t.Fatalf("limit = %d, want %d", got.limit, config.DefaultPGStatUserFunctionsLimit)
+	}
+	if got.includeSchemas[0] != "billing" {
+		t.Fatalf("includeSchemas[0] = %q, want billing", got.includeSchemas[0])
+	}
+	if got.excludeFunctions[0] != "refund" {
+		t.Fatalf("excludeFunctions[0] = %q, want refund", got.excludeFunctions[0])
+	}
+}
+
+func TestNewPGPreparedXactsCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGPreparedXactsCollector(collectorConfig{
+		logger:        promslog.NewNopLogger(),
+		preparedXacts: config.PreparedXactsConfig{IncludeGID: true},
+	})
+	if err != nil {
+		t.Fatalf("NewPGPreparedXactsCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGPreparedXactsCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGPreparedXactsCollector", collector)
+	}
+	if !got.includeGID {
+		t.Fatal("includeGID = false, want true")
+	}
+	if got.gidLimit != config.DefaultPreparedXactsGIDLimit {
+		t.Fatalf("gidLimit = %d, want %d", got.gidLimit, config.DefaultPreparedXactsGIDLimit)
+	}
+}
+
+func TestNewPGSettingsCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGSettingsCollector(collectorConfig{
+		logger:   promslog.NewNopLogger(),
+		settings: config.SettingsConfig{Audit: true, Info: []string{"*"}, Hash: true},
+	})
+	if err != nil {
+		t.Fatalf("NewPGSettingsCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGSettingsCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGSettingsCollector", collector)
+	}
+	if !got.audit {
+		t.Fatal("audit = false, want true")
+	}
+	if len(got.info) != 1 || got.info[0] != "*" {
+		t.Fatalf("info = %v, want [*]", got.info)
+	}
+	if !got.hash {
+		t.Fatal("hash = false, want true")
+	}
+}
+
+func TestNewPGTablespaceCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGTablespaceCollector(collectorConfig{
+		logger:     promslog.NewNopLogger(),
+		tablespace: config.TablespaceConfig{PerDatabase: true},
+	})
+	if err != nil {
+		t.Fatalf("NewPGTablespaceCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGTablespaceCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGTablespaceCollector", collector)
+	}
+	if !got.perDatabase {
+		t.Fatal("perDatabase = false, want true")
+	}
+}
+
+func TestNewPGTableWraparoundCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGTableWraparoundCollector(collectorConfig{
+		logger:          promslog.NewNopLogger(),
+		tableWraparound: config.TableWraparoundConfig{},
+	})
+	if err != nil {
+		t.Fatalf("NewPGTableWraparoundCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGTableWraparoundCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGTableWraparoundCollector", collector)
+	}
+	if got.limit != config.DefaultTableWraparoundLimit {
+		t.Fatalf("limit = %d, want %d", got.limit, config.DefaultTableWraparoundLimit)
+	}
+}
+
+func TestNewPGBloatCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGBloatCollector(collectorConfig{
+		logger: promslog.NewNopLogger(),
+		bloat:  config.BloatConfig{Mode: config.BloatModeExact, CacheTTL: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("NewPGBloatCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGBloatCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGBloatCollector", collector)
+	}
+	if got.mode != config.BloatModeExact {
+		t.Fatalf("mode = %q, want %q", got.mode, config.BloatModeExact)
+	}
+	if got.limit != config.DefaultBloatLimit {
+		t.Fatalf("limit = %d, want %d", got.limit, config.DefaultBloatLimit)
+	}
+	if got.cacheTTL != time.Hour {
+		t.Fatalf("cacheTTL = %s, want %s", got.cacheTTL, time.Hour)
+	}
+}
+
+func TestNewPGIndexHealthCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGIndexHealthCollector(collectorConfig{
+		logger:      promslog.NewNopLogger(),
+		indexHealth: config.IndexHealthConfig{IncludeIndexes: true},
+	})
+	if err != nil {
+		t.Fatalf("NewPGIndexHealthCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGIndexHealthCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGIndexHealthCollector", collector)
+	}
+	if !got.includeIndexes {
+		t.Fatal("includeIndexes = false, want true")
+	}
+	if got.indexLimit != config.DefaultIndexHealthIndexLimit {
+		t.Fatalf("indexLimit = %d, want %d", got.indexLimit, config.DefaultIndexHealthIndexLimit)
+	}
+}
+
+func TestNewPGSequencesCollectorUsesConfig(t *testing.T) {
+	collector, err := NewPGSequencesCollector(collectorConfig{
+		logger:    promslog.NewNopLogger(),
+		sequences: config.SequencesConfig{Threshold: 0.9},
+	})
+	if err != nil {
+		t.Fatalf("NewPGSequencesCollector() error = %v", err)
+	}
+	got, ok := collector.(*PGSequencesCollector)
+	if !ok {
+		t.Fatalf("collector type = %T, want *PGSequencesCollector", collector)
+	}
+	if got.threshold != 0.9 {
+		t.Fatalf("threshold = %g, want 0.9", got.threshold)
+	}
+	if got.limit != config.DefaultSequencesLimit {
+		t.Fatalf("limit = %d, want %d", got.limit, config.DefaultSequencesLimit)
+	}
+}
@@ -17,24 +17,34 @@ import (
 	"database/sql"
 	"fmt"
 	"regexp"
+	"sync"
 
 	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
 )
 
 type instance struct {
-	dsn     string
-	db      *sql.DB
-	version semver.Version
+	dsn             string
+	credentialFiles config.CredentialFiles
+	db              *sql.DB
+	version         semver.Version
+
+	// observeTLS makes setup record the TLS handshakes of the driver for
+	// the server_certificate collector.
+	observeTLS            bool
+	tlsMu                 sync.Mutex
+	tlsHandshakesByServer map[string]config.TLSHandshake
 }
 
-func newInstance(dsn string) (*instance, error) {
+func newInstance(dsn string, credentialFiles config.CredentialFiles) (*instance, error) {
 	i := &instance{
-		dsn: dsn,
+		dsn:             dsn,
+		credentialFiles: credentialFiles,
 	}
 
 	// "Create" a database handle to verify the DSN provided is valid.
 	// Open is not guaranteed to create a connection.
-	db, err := sql.Open("postgres", dsn)
+	db, err := config.OpenDB(dsn, credentialFiles)
 	if err != nil {
 		return nil, err
 	}
@@ -46,12 +56,20 @@ func newInstance(dsn string) (*instance, error) {
 // copy returns a copy of the instance.
 func (i *instance) copy() *instance {
 	return &instance{
-		dsn: i.dsn,
+		dsn:             i.dsn,
+		credentialFiles: i.credentialFiles,
+		observeTLS:      i.observeTLS,
 	}
 }
 
 func (i *instance) setup() error {
-	db, err := sql.Open("postgres", i.dsn)
+	var db *sql.DB
+	var err error
+	if i.observeTLS {
+		db, err = config.OpenDBObservingTLS(i.dsn, i.credentialFiles, i.observeTLSHandshake)
+	} else {
+		db, err = config.OpenDB(i.dsn, i.credentialFiles)
+	}
 	if err != nil {
 		return err
 	}
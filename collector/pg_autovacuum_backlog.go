@@ -0,0 +1,246 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because it evaluates the thresholds of every
+	//   table of the database on each scrape
+	registerCollector(autovacuumBacklogSubsystem, NewPGAutovacuumBacklogCollector)
+}
+
+type PGAutovacuumBacklogCollector struct {
+	log *slog.Logger
+}
+
+func NewPGAutovacuumBacklogCollector(config collectorConfig) (Collector, error) {
+	return &PGAutovacuumBacklogCollector{log: config.logger}, nil
+}
+
+var (
+	autovacuumBacklogVacuumOverdueTables = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, autovacuumBacklogSubsystem, "vacuum_overdue_tables"),
+		"Number of tables over their autovacuum vacuum or insert threshold",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	autovacuumBacklogVacuumOverdueDeadTuples = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, autovacuumBacklogSubsystem, "vacuum_overdue_dead_tuples"),
+		"Estimated number of dead tuples in the tables over their autovacuum vacuum or insert threshold",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	autovacuumBacklogAnalyzeOverdueTables = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, autovacuumBacklogSubsystem, "analyze_overdue_tables"),
+		"Number of tables over their autovacuum analyze threshold",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	autovacuumBacklogDisabledTables = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, autovacuumBacklogSubsystem, "disabled_tables"),
+		"Number of tables with autovacuum disabled by the autovacuum_enabled storage parameter",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	autovacuumBacklogOldestVacuumOverdueAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, autovacuumBacklogSubsystem, "oldest_vacuum_overdue_age_seconds"),
+		"Time since the table over its vacuum threshold that has waited longest was last vacuumed, or since statistics were reset if it never was",
+		[]string{"datname", "schemaname", "relname"},
+		prometheus.Labels{},
+	)
+
+	// The thresholds follow relation_needs_vacanalyze() in the autovacuum
+	// launcher: the storage parameters of a table override the settings, a
+	// TOAST table without storage parameters of its own uses those of its
+	// table, and TOAST tables are never analyzed. Tables with autovacuum
+	// disabled are counted apart rather than as overdue, since autovacuum
+	// only processes them to prevent wraparound.
+	//
+	// The format verbs fill in what depends on the server version:
+	//   1. n_ins_since_vacuum, or 0 before Postgres 13
+	//   2. autovacuum_vacuum_insert_threshold, or -1 (disabled) before 13
+	//   3. autovacuum_vacuum_insert_scale_factor, or 0 before 13
+	//   4. autovacuum_vacuum_max_threshold, or -1 (disabled) before 18
+	//   5. the fraction of the table not all-frozen, or 1 before 18
+	autovacuumBacklogQueryTemplate = `
+	WITH rels AS (
+		SELECT
+			s.schemaname,
+			s.relname,
+			c.relkind,
+			GREATEST(c.reltuples, 0) AS reltuples,
+			%[5]s AS unfrozen,
+			s.n_dead_tup,
+			s.n_mod_since_analyze,
+			%[1]s AS n_ins_since_vacuum,
+			GREATEST(s.last_vacuum, s.last_autovacuum) AS last_vacuum,
+			COALESCE(c.reloptions, m.reloptions) AS reloptions
+		FROM pg_catalog.pg_stat_all_tables s
+		JOIN pg_catalog.pg_class c ON c.oid = s.relid
+		LEFT JOIN pg_catalog.pg_class m ON c.relkind = 't' AND m.reltoastrelid = c.oid
+		WHERE c.relkind IN ('r', 'm', 't')
+			AND c.relpersistence <> 't'
+	), thresholds AS (
+		SELECT
+			r.*,
+			COALESCE(o.enabled, true) AS enabled,
+			COALESCE(o.vac_base, current_setting('autovacuum_vacuum_threshold')::float8)
+				+ COALESCE(o.vac_scale, current_setting('autovacuum_vacuum_scale_factor')::float8) * r.reltuples AS vac_thresh,
+			COALESCE(o.vac_max, %[4]s) AS vac_max,
+			COALESCE(o.ins_base, %[2]s) AS ins_base,
+			COALESCE(o.ins_scale, %[3]s) * r.reltuples * r.unfrozen AS ins_scaled,
+			COALESCE(o.anl_base, current_setting('autovacuum_analyze_threshold')::float8)
+				+ COALESCE(o.anl_scale, current_setting('autovacuum_analyze_scale_factor')::float8) * r.reltuples AS anl_thresh
+		FROM rels r
+		CROSS JOIN LATERAL (
+			SELECT
+				bool_and(option_value::boolean) FILTER (WHERE option_name = 'autovacuum_enabled') AS enabled,
+				max(option_value::float8) FILTER (WHERE option_name = 'autovacuum_vacuum_threshold') AS vac_base,
+				max(option_value::float8) FILTER (WHERE option_name = 'autovacuum_vacuum_scale_factor') AS vac_scale,
+				max(option_value::float8) FILTER (WHERE option_name = 'autovacuum_vacuum_max_threshold') AS vac_max,
+				max(option_value::float8) FILTER (WHERE option_name = 'autovacuum_vacuum_insert_threshold') AS ins_base,
+				max(option_value::float8) FILTER (WHERE option_name = 'autovacuum_vacuum_insert_scale_factor') AS ins_scale,
+				max(option_value::float8) FILTER (WHERE option_name = 'autovacuum_analyze_threshold') AS anl_base,
+				max(option_value::float8) FILTER (WHERE option_name = 'autovacuum_analyze_scale_factor') AS anl_scale
+			FROM pg_options_to_table(r.reloptions)
+		) o
+	), due AS (
+		SELECT
+			t.schemaname,
+			t.relname,
+			t.n_dead_tup,
+			t.last_vacuum,
+			t.enabled AND (
+				t.n_dead_tup > CASE WHEN t.vac_max >= 0 THEN LEAST(t.vac_thresh, t.vac_max) ELSE t.vac_thresh END
+				OR (t.ins_base >= 0 AND t.n_ins_since_vacuum > t.ins_base + t.ins_scaled)
+			) AS vacuum_due,
+			t.enabled AND t.relkind <> 't' AND t.n_mod_since_analyze > t.anl_thresh AS analyze_due,
+			NOT t.enabled AS disabled
+		FROM thresholds t
+	)
+	SELECT
+		current_database() AS datname,
+		a.vacuum_overdue_tables,
+		a.vacuum_overdue_dead_tuples,
+		a.analyze_overdue_tables,
+		a.disabled_tables,
+		oldest.schemaname,
+		oldest.relname,
+		oldest.age_seconds
+	FROM (
+		SELECT
+			count(*) FILTER (WHERE vacuum_due) AS vacuum_overdue_tables,
+			COALESCE(sum(n_dead_tup) FILTER (WHERE vacuum_due), 0) AS vacuum_overdue_dead_tuples,
+			count(*) FILTER (WHERE analyze_due) AS analyze_overdue_tables,
+			count(*) FILTER (WHERE disabled) AS disabled_tables
+		FROM due
+	) a
+	LEFT JOIN (
+		SELECT
+			schemaname,
+			relname,
+			EXTRACT(EPOCH FROM now() - COALESCE(
+				last_vacuum,
+				(SELECT stats_reset FROM pg_catalog.pg_stat_database WHERE datname = current_database()),
+				pg_postmaster_start_time()
+			)) AS age_seconds
+		FROM due
+		WHERE vacuum_due
+		ORDER BY age_seconds DESC, schemaname, relname
+		LIMIT 1
+	) oldest ON true
+	`
+)
+
+func autovacuumBacklogQuery(version semver.Version) string {
+	insSinceVacuum, insBase, insScale := "0", "-1", "0"
+	if version.GTE(semver.MustParse("13.0.0")) {
+		insSinceVacuum = "s.n_ins_since_vacuum"
+		insBase = "current_setting('autovacuum_vacuum_insert_threshold')::float8"
+		insScale = "current_setting('autovacuum_vacuum_insert_scale_factor')::float8"
+	}
+	vacMax, unfrozen := "-1", "1"
+	if version.GTE(semver.MustParse("18.0.0")) {
+		vacMax = "current_setting('autovacuum_vacuum_max_threshold')::float8"
+		unfrozen = "CASE WHEN c.relpages > 0 THEN 1 - LEAST(c.relallfrozen, c.relpages)::float8 / c.relpages ELSE 1 END"
+	}
+	return fmt.Sprintf(autovacuumBacklogQueryTemplate, insSinceVacuum, insBase, insScale, vacMax, unfrozen)
+}
+
+func (c *PGAutovacuumBacklogCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("9.4.0")) {
+		c.log.Warn("autovacuum_backlog collector is not available on PostgreSQL < 9.4.0, skipping")
+		return nil
+	}
+
+	db := instance.getDB()
+	row := db.QueryRowContext(ctx, autovacuumBacklogQuery(instance.version))
+
+	var datname string
+	var vacuumOverdueTables, vacuumOverdueDeadTuples, analyzeOverdueTables, disabledTables sql.NullFloat64
+	var oldestSchemaname, oldestRelname sql.NullString
+	var oldestAge sql.NullFloat64
+	if err := row.Scan(
+		&datname,
+		&vacuumOverdueTables, &vacuumOverdueDeadTuples, &analyzeOverdueTables, &disabledTables,
+		&oldestSchemaname, &oldestRelname, &oldestAge,
+	); err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		autovacuumBacklogVacuumOverdueTables,
+		prometheus.GaugeValue,
+		vacuumOverdueTables.Float64,
+		datname,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		autovacuumBacklogVacuumOverdueDeadTuples,
+		prometheus.GaugeValue,
+		vacuumOverdueDeadTuples.Float64,
+		datname,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		autovacuumBacklogAnalyzeOverdueTables,
+		prometheus.GaugeValue,
+		analyzeOverdueTables.Float64,
+		datname,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		autovacuumBacklogDisabledTables,
+		prometheus.GaugeValue,
+		disabledTables.Float64,
+		datname,
+	)
+	// No table is over its vacuum threshold.
+	if oldestAge.Valid {
+		ch <- prometheus.MustNewConstMetric(
+			autovacuumBacklogOldestVacuumOverdueAge,
+			prometheus.GaugeValue,
+			oldestAge.Float64,
+			datname, oldestSchemaname.String, oldestRelname.String,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+var autovacuumBacklogColumns = []string{
+	"datname",
+	"vacuum_overdue_tables",
+	"vacuum_overdue_dead_tuples",
+	"analyze_overdue_tables",
+	"disabled_tables",
+	"schemaname",
+	"relname",
+	"age_seconds",
+}
+
+func TestPGAutovacuumBacklogCollector(t *testing.T) {
+	rows := sqlmock.NewRows(autovacuumBacklogColumns).
+		AddRow("orders", 3, 1250000, 5, 1, "public", "events", 86400.5)
+
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGAutovacuumBacklogCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(autovacuumBacklogQuery(inst.version))).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 3},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 1250000},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 5},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events"}, metricType: dto.MetricType_GAUGE, value: 86400.5},
+	})
+}
+
+func TestPGAutovacuumBacklogCollectorNothingOverdue(t *testing.T) {
+	rows := sqlmock.NewRows(autovacuumBacklogColumns).
+		AddRow("orders", 0, 0, 0, 0, nil, nil, nil)
+
+	inst := &instance{version: semver.MustParse("12.0.0")}
+	testCollectorUpdate(t, &PGAutovacuumBacklogCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(autovacuumBacklogQuery(inst.version))).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 0},
+	})
+}
+
+func TestPGAutovacuumBacklogCollectorBefore94(t *testing.T) {
+	inst := &instance{version: semver.MustParse("9.3.0")}
+	testCollectorUpdate(t, &PGAutovacuumBacklogCollector{log: promslog.NewNopLogger()}, inst, nil, nil)
+}
+
+func TestAutovacuumBacklogQueryVersions(t *testing.T) {
+	for _, tc := range []struct {
+		version  string
+		insert   bool
+		maxThres bool
+	}{
+		{version: "12.0.0"},
+		{version: "13.0.0", insert: true},
+		{version: "18.0.0", insert: true, maxThres: true},
+	} {
+		query := autovacuumBacklogQuery(semver.MustParse(tc.version))
+		if got := strings.Contains(query, "s.n_ins_since_vacuum"); got != tc.insert {
+			t.Errorf("%s: query uses n_ins_since_vacuum = %t, want %t", tc.version, got, tc.insert)
+		}
+		if got := strings.Contains(query, "current_setting('autovacuum_vacuum_max_threshold')"); got != tc.maxThres {
+			t.Errorf("%s: query uses autovacuum_vacuum_max_threshold = %t, want %t", tc.version, got, tc.maxThres)
+		}
+		if strings.Contains(query, "%!") {
+			t.Errorf("%s: query has formatting errors:\n%s", tc.version, query)
+		}
+	}
+}
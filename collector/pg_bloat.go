@@ -0,0 +1,514 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because the estimate reads the statistics of
+	//   every column of the database, and the exact mode reads every table
+	//   and index
+	registerCollector(bloatSubsystem, NewPGBloatCollector)
+}
+
+func defaultBloatConfig() config.BloatConfig {
+	return config.BloatConfig{
+		Mode:     config.DefaultBloatMode,
+		Limit:    config.DefaultBloatLimit,
+		CacheTTL: config.DefaultBloatCacheTTL,
+	}
+}
+
+func withBloatDefaults(c config.BloatConfig) config.BloatConfig {
+	if c.Mode == "" {
+		c.Mode = config.DefaultBloatMode
+	}
+	if c.Limit == 0 {
+		c.Limit = config.DefaultBloatLimit
+	}
+	return c
+}
+
+type PGBloatCollector struct {
+	log      *slog.Logger
+	mode     string
+	limit    uint
+	cacheTTL time.Duration
+}
+
+func NewPGBloatCollector(collectorCfg collectorConfig) (Collector, error) {
+	cfg := withBloatDefaults(collectorCfg.bloat)
+	if cfg.Mode == config.BloatModeExact && cfg.CacheTTL == 0 {
+		collectorCfg.logger.Warn("bloat collector exact mode reads every table and index on each scrape, set collector.bloat.cache_ttl to measure less often")
+	}
+	return &PGBloatCollector{
+		log:      collectorCfg.logger,
+		mode:     cfg.Mode,
+		limit:    cfg.Limit,
+		cacheTTL: cfg.CacheTTL,
+	}, nil
+}
+
+// bloatCacheKey identifies the measurements of a target. The cache is shared
+// by all collectors because a new collector is created for every probe.
+type bloatCacheKey struct {
+	dsn   string
+	mode  string
+	limit uint
+}
+
+type bloatCacheEntry struct {
+	metrics []prometheus.Metric
+	expires time.Time
+}
+
+var (
+	bloatCacheMu sync.Mutex
+	bloatCache   = make(map[bloatCacheKey]bloatCacheEntry)
+)
+
+var (
+	bloatTableBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, bloatSubsystem, "table_bytes"),
+		"Estimated disk space of the table taken by dead tuples and free space beyond its fillfactor",
+		[]string{"datname", "schemaname", "relname"},
+		prometheus.Labels{},
+	)
+	bloatTableRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, bloatSubsystem, "table_ratio"),
+		"Estimated bloat of the table as a ratio of its size",
+		[]string{"datname", "schemaname", "relname"},
+		prometheus.Labels{},
+	)
+	bloatIndexBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, bloatSubsystem, "index_bytes"),
+		"Estimated disk space of the btree index beyond what its entries need at its fillfactor",
+		[]string{"datname", "schemaname", "relname", "indexrelname"},
+		prometheus.Labels{},
+	)
+	bloatIndexRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, bloatSubsystem, "index_ratio"),
+		"Estimated bloat of the btree index as a ratio of its size",
+		[]string{"datname", "schemaname", "relname", "indexrelname"},
+		prometheus.Labels{},
+	)
+
+	// The estimates are the widely used ones from
+	// https://github.com/ioguix/pgsql-bloat-estimation. They compute the
+	// number of pages the rows would need from the average column widths
+	// and null fractions in pg_stats, and compare it with relpages. Tables
+	// with columns of type name or without statistics on every column are
+	// left out, since their estimate is unreliable.
+	bloatTableEstimateQuery = `
+	SELECT
+		current_database() AS datname,
+		schemaname,
+		relname,
+		CASE WHEN tblpages > est_tblpages_ff THEN (tblpages - est_tblpages_ff) * bs ELSE 0 END AS bloat_bytes,
+		CASE WHEN tblpages > est_tblpages_ff THEN (tblpages - est_tblpages_ff)::float8 / tblpages ELSE 0 END AS bloat_ratio
+	FROM (
+		SELECT
+			ceil(reltuples / ((bs - page_hdr) * fillfactor / (tpl_size * 100))) + ceil(toasttuples / 4) AS est_tblpages_ff,
+			heappages + toastpages AS tblpages,
+			bs,
+			schemaname,
+			relname,
+			is_na
+		FROM (
+			SELECT
+				(4 + tpl_hdr_size + tpl_data_size + (2 * ma)
+					- CASE WHEN mod(tpl_hdr_size, ma) = 0 THEN ma ELSE mod(tpl_hdr_size, ma) END
+					- CASE WHEN mod(ceil(tpl_data_size)::int, ma) = 0 THEN ma ELSE mod(ceil(tpl_data_size)::int, ma) END
+				) AS tpl_size,
+				heappages,
+				toastpages,
+				reltuples,
+				toasttuples,
+				bs,
+				page_hdr,
+				schemaname,
+				relname,
+				fillfactor,
+				is_na
+			FROM (
+				SELECT
+					ns.nspname AS schemaname,
+					tbl.relname,
+					tbl.reltuples,
+					tbl.relpages AS heappages,
+					COALESCE(toast.relpages, 0) AS toastpages,
+					COALESCE(toast.reltuples, 0) AS toasttuples,
+					COALESCE(substring(array_to_string(tbl.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 100) AS fillfactor,
+					current_setting('block_size')::numeric AS bs,
+					CASE WHEN version() ~ 'mingw32' OR version() ~ '64-bit|x86_64|ppc64|ia64|amd64' THEN 8 ELSE 4 END AS ma,
+					24 AS page_hdr,
+					23 + CASE WHEN max(COALESCE(s.null_frac, 0)) > 0 THEN (7 + count(s.attname)) / 8 ELSE 0::int END AS tpl_hdr_size,
+					sum((1 - COALESCE(s.null_frac, 0)) * COALESCE(s.avg_width, 0)) AS tpl_data_size,
+					bool_or(att.atttypid = 'pg_catalog.name'::regtype)
+						OR sum(CASE WHEN att.attnum > 0 THEN 1 ELSE 0 END) <> count(s.attname) AS is_na
+				FROM pg_catalog.pg_attribute att
+				JOIN pg_catalog.pg_class tbl ON att.attrelid = tbl.oid
+				JOIN pg_catalog.pg_namespace ns ON ns.oid = tbl.relnamespace
+				LEFT JOIN pg_catalog.pg_stats s
+					ON s.schemaname = ns.nspname
+					AND s.tablename = tbl.relname
+					AND NOT s.inherited
+					AND s.attname = att.attname
+				LEFT JOIN pg_catalog.pg_class toast ON tbl.reltoastrelid = toast.oid
+				WHERE NOT att.attisdropped
+					AND att.attnum > 0
+					AND tbl.relkind IN ('r', 'm')
+					AND ns.nspname NOT IN ('pg_catalog', 'information_schema')
+				GROUP BY 1, 2, 3, 4, 5, 6, 7, 8, 9, 10
+			) AS tbl_stats
+		) AS tpl_sizes
+	) AS est
+	WHERE NOT is_na
+		AND tblpages > 0
+	ORDER BY bloat_bytes DESC, schemaname, relname
+	LIMIT $1
+	`
+
+	bloatIndexEstimateQuery = `
+	SELECT
+		current_database() AS datname,
+		nspname AS schemaname,
+		tblname AS relname,
+		idxname AS indexrelname,
+		CASE WHEN relpages > est_pages_ff THEN bs * (relpages - est_pages_ff) ELSE 0 END AS bloat_bytes,
+		CASE WHEN relpages > est_pages_ff THEN (relpages - est_pages_ff)::float8 / relpages ELSE 0 END AS bloat_ratio
+	FROM (
+		SELECT
+			COALESCE(1 + ceil(reltuples / floor((bs - pageopqdata - pagehdr) * fillfactor / (100 * (4 + nulldatahdrwidth)::float8))), 0) AS est_pages_ff,
+			bs,
+			nspname,
+			tblname,
+			idxname,
+			relpages,
+			is_na
+		FROM (
+			SELECT
+				bs,
+				nspname,
+				tblname,
+				idxname,
+				reltuples,
+				relpages,
+				fillfactor,
+				(index_tuple_hdr_bm
+					+ maxalign - CASE WHEN mod(index_tuple_hdr_bm, maxalign) = 0 THEN maxalign ELSE mod(index_tuple_hdr_bm, maxalign) END
+					+ nulldatawidth
+					+ maxalign - CASE
+						WHEN nulldatawidth = 0 THEN 0
+						WHEN mod(nulldatawidth::integer, maxalign) = 0 THEN maxalign
+						ELSE mod(nulldatawidth::integer, maxalign)
+					END
+				)::numeric AS nulldatahdrwidth,
+				pagehdr,
+				pageopqdata,
+				is_na
+			FROM (
+				SELECT
+					n.nspname,
+					i.tblname,
+					i.idxname,
+					i.reltuples,
+					i.relpages,
+					i.fillfactor,
+					current_setting('block_size')::numeric AS bs,
+					CASE WHEN version() ~ 'mingw32' OR version() ~ '64-bit|x86_64|ppc64|ia64|amd64' THEN 8 ELSE 4 END AS maxalign,
+					24 AS pagehdr,
+					16 AS pageopqdata,
+					CASE WHEN max(COALESCE(s.null_frac, 0)) = 0 THEN 8 ELSE 8 + ((32 + 8 - 1) / 8) END AS index_tuple_hdr_bm,
+					sum((1 - COALESCE(s.null_frac, 0)) * COALESCE(s.avg_width, 1024)) AS nulldatawidth,
+					bool_or(i.atttypid = 'pg_catalog.name'::regtype) AS is_na
+				FROM (
+					SELECT
+						ct.relname AS tblname,
+						ct.relnamespace,
+						ic.idxname,
+						ic.reltuples,
+						ic.relpages,
+						ic.fillfactor,
+						COALESCE(a1.attname, a2.attname) AS attname,
+						COALESCE(a1.atttypid, a2.atttypid) AS atttypid,
+						CASE WHEN a1.attnum IS NULL THEN ic.idxname ELSE ct.relname END AS attrelname
+					FROM (
+						SELECT
+							ci.relname AS idxname,
+							ci.reltuples,
+							ci.relpages,
+							i.indrelid AS tbloid,
+							i.indexrelid AS idxoid,
+							COALESCE(substring(array_to_string(ci.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 90) AS fillfactor,
+							string_to_array(textin(int2vectorout(i.indkey)), ' ')::int[] AS indkey,
+							generate_series(1, i.indnatts) AS attpos
+						FROM pg_catalog.pg_index i
+						JOIN pg_catalog.pg_class ci ON ci.oid = i.indexrelid
+						JOIN pg_catalog.pg_am am ON am.oid = ci.relam
+						WHERE am.amname = 'btree'
+							AND ci.relpages > 0
+					) AS ic
+					JOIN pg_catalog.pg_class ct ON ct.oid = ic.tbloid
+					LEFT JOIN pg_catalog.pg_attribute a1
+						ON ic.indkey[ic.attpos] <> 0
+						AND a1.attrelid = ic.tbloid
+						AND a1.attnum = ic.indkey[ic.attpos]
+					LEFT JOIN pg_catalog.pg_attribute a2
+						ON ic.indkey[ic.attpos] = 0
+						AND a2.attrelid = ic.idxoid
+						AND a2.attnum = ic.attpos
+				) AS i
+				JOIN pg_catalog.pg_namespace n ON n.oid = i.relnamespace
+				JOIN pg_catalog.pg_stats s
+					ON s.schemaname = n.nspname
+					AND s.tablename = i.attrelname
+					AND s.attname = i.attname
+				WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+				GROUP BY 1, 2, 3, 4, 5, 6, 7, 8, 9, 10
+			) AS idx_stats
+		) AS idx_sizes
+	) AS est
+	WHERE NOT is_na
+	ORDER BY bloat_bytes DESC, schemaname, relname, indexrelname
+	LIMIT $1
+	`
+
+	// The exact mode is skipped in databases without pgstattuple, instead of
+	// failing on every scrape.
+	bloatPgstattupleQuery = `SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_extension WHERE extname = 'pgstattuple')`
+
+	// pgstattuple_approx() reads the pages not marked all-visible, and
+	// reports the free space and dead tuples of the table. The free space
+	// the fillfactor reserves is not bloat. OFFSET 0 keeps the planner from
+	// calling the function before the relations are filtered. Unlogged
+	// relations cannot be read on a standby.
+	bloatTableExactQuery = `
+	SELECT
+		current_database() AS datname,
+		schemaname,
+		relname,
+		bloat_bytes,
+		bloat_bytes / table_len AS bloat_ratio
+	FROM (
+		SELECT
+			r.schemaname,
+			r.relname,
+			s.table_len::float8 AS table_len,
+			GREATEST(s.approx_free_space + s.dead_tuple_len - s.table_len * (100 - r.fillfactor) / 100.0, 0)::float8 AS bloat_bytes
+		FROM (
+			SELECT
+				c.oid,
+				n.nspname AS schemaname,
+				c.relname,
+				COALESCE(substring(array_to_string(c.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 100) AS fillfactor
+			FROM pg_catalog.pg_class c
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'm')
+				AND c.relpersistence <> 't'
+				AND (c.relpersistence = 'p' OR NOT pg_is_in_recovery())
+				AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			OFFSET 0
+		) AS r
+		CROSS JOIN LATERAL pgstattuple_approx(r.oid::regclass) s
+	) AS t
+	WHERE table_len > 0
+	ORDER BY bloat_bytes DESC, schemaname, relname
+	LIMIT $1
+	`
+
+	// pgstatindex() reads the whole index, and fails on anything but a valid
+	// btree index with storage, which OFFSET 0 makes sure is filtered first.
+	// Partitioned indexes have the btree access method too, but no storage.
+	// The leaf pages are expected to be filled up to the fillfactor, the rest
+	// of the index is bloat.
+	bloatIndexExactQuery = `
+	SELECT
+		current_database() AS datname,
+		schemaname,
+		relname,
+		indexrelname,
+		index_size * bloat_ratio AS bloat_bytes,
+		bloat_ratio
+	FROM (
+		SELECT
+			r.schemaname,
+			r.relname,
+			r.indexrelname,
+			s.index_size::float8 AS index_size,
+			GREATEST(1 - s.avg_leaf_density / r.fillfactor, 0) AS bloat_ratio
+		FROM (
+			SELECT
+				c.oid,
+				n.nspname AS schemaname,
+				t.relname,
+				c.relname AS indexrelname,
+				COALESCE(substring(array_to_string(c.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 90) AS fillfactor
+			FROM pg_catalog.pg_index i
+			JOIN pg_catalog.pg_class c ON c.oid = i.indexrelid
+			JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_catalog.pg_am am ON am.oid = c.relam
+			WHERE am.amname = 'btree'
+				AND c.relkind = 'i'
+				AND i.indisvalid
+				AND c.relpersistence <> 't'
+				AND (c.relpersistence = 'p' OR NOT pg_is_in_recovery())
+				AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			OFFSET 0
+		) AS r
+		CROSS JOIN LATERAL pgstatindex(r.oid::regclass) s
+		WHERE s.leaf_pages > 0
+	) AS i
+	ORDER BY bloat_bytes DESC, schemaname, relname, indexrelname
+	LIMIT $1
+	`
+)
+
+func (c *PGBloatCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	tableQuery, indexQuery := bloatTableEstimateQuery, bloatIndexEstimateQuery
+	if c.mode == config.BloatModeExact {
+		if instance.version.LT(semver.MustParse("9.5.0")) {
+			c.log.Warn("bloat collector exact mode is not available on PostgreSQL < 9.5.0, skipping")
+			return nil
+		}
+		tableQuery, indexQuery = bloatTableExactQuery, bloatIndexExactQuery
+	}
+
+	key := bloatCacheKey{dsn: instance.dsn, mode: c.mode, limit: c.limit}
+	now := time.Now()
+	if c.cacheTTL > 0 {
+		bloatCacheMu.Lock()
+		entry, ok := bloatCache[key]
+		bloatCacheMu.Unlock()
+		if ok && now.Before(entry.expires) {
+			for _, m := range entry.metrics {
+				ch <- m
+			}
+			return nil
+		}
+	}
+
+	if c.mode == config.BloatModeExact {
+		var installed bool
+		if err := instance.getDB().QueryRowContext(ctx, bloatPgstattupleQuery).Scan(&installed); err != nil {
+			return err
+		}
+		if !installed {
+			c.log.Warn("bloat collector exact mode requires the pgstattuple extension in the database, skipping")
+			return nil
+		}
+	}
+
+	metrics, err := c.updateTables(ctx, instance, tableQuery, nil)
+	if err != nil {
+		return err
+	}
+	metrics, err = c.updateIndexes(ctx, instance, indexQuery, metrics)
+	if err != nil {
+		return err
+	}
+
+	if c.cacheTTL > 0 {
+		bloatCacheMu.Lock()
+		for k, e := range bloatCache {
+			if !now.Before(e.expires) {
+				delete(bloatCache, k)
+			}
+		}
+		bloatCache[key] = bloatCacheEntry{metrics: metrics, expires: now.Add(c.cacheTTL)}
+		bloatCacheMu.Unlock()
+	}
+	for _, m := range metrics {
+		ch <- m
+	}
+	return nil
+}
+
+func (c *PGBloatCollector) updateTables(ctx context.Context, instance *instance, query string, metrics []prometheus.Metric) ([]prometheus.Metric, error) {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, query, c.limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, schemaname, relname string
+		var bloatBytes, bloatRatio sql.NullFloat64
+		if err := rows.Scan(&datname, &schemaname, &relname, &bloatBytes, &bloatRatio); err != nil {
+			return nil, err
+		}
+
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(
+				bloatTableBytes,
+				prometheus.GaugeValue,
+				bloatBytes.Float64,
+				datname, schemaname, relname,
+			),
+			prometheus.MustNewConstMetric(
+				bloatTableRatio,
+				prometheus.GaugeValue,
+				bloatRatio.Float64,
+				datname, schemaname, relname,
+			),
+		)
+	}
+	return metrics, rows.Err()
+}
+
+func (c *PGBloatCollector) updateIndexes(ctx context.Context, instance *instance, query string, metrics []prometheus.Metric) ([]prometheus.Metric, error) {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, query, c.limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, schemaname, relname, indexrelname string
+		var bloatBytes, bloatRatio sql.NullFloat64
+		if err := rows.Scan(&datname, &schemaname, &relname, &indexrelname, &bloatBytes, &bloatRatio); err != nil {
+			return nil, err
+		}
+
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(
+				bloatIndexBytes,
+				prometheus.GaugeValue,
+				bloatBytes.Float64,
+				datname, schemaname, relname, indexrelname,
+			),
+			prometheus.MustNewConstMetric(
+				bloatIndexRatio,
+				prometheus.GaugeValue,
+				bloatRatio.Float64,
+				datname, schemaname, relname, indexrelname,
+			),
+		)
+	}
+	return metrics, rows.Err()
+}
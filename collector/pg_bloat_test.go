@@ -0,0 +1,107 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+var (
+	bloatTableColumns = []string{"datname", "schemaname", "relname", "bloat_bytes", "bloat_ratio"}
+	bloatIndexColumns = []string{"datname", "schemaname", "relname", "indexrelname", "bloat_bytes", "bloat_ratio"}
+)
+
+// expectBloatQueries expects the table and index queries with the limit and
+// answers them with the measurements in bloatExpected.
+func expectBloatQueries(tableQuery, indexQuery string, limit uint) func(sqlmock.Sqlmock) {
+	return func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(tableQuery)).WithArgs(limit).WillReturnRows(
+			sqlmock.NewRows(bloatTableColumns).
+				AddRow("orders", "public", "events", 805306368, 0.75).
+				AddRow("orders", "public", "customers", 0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(indexQuery)).WithArgs(limit).WillReturnRows(
+			sqlmock.NewRows(bloatIndexColumns).
+				AddRow("orders", "public", "events", "events_created_at_idx", 134217728, 0.5))
+	}
+}
+
+var bloatExpected = []MetricResult{
+	{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events"}, metricType: dto.MetricType_GAUGE, value: 805306368},
+	{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events"}, metricType: dto.MetricType_GAUGE, value: 0.75},
+	{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "customers"}, metricType: dto.MetricType_GAUGE, value: 0},
+	{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "customers"}, metricType: dto.MetricType_GAUGE, value: 0},
+	{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events", "indexrelname": "events_created_at_idx"}, metricType: dto.MetricType_GAUGE, value: 134217728},
+	{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events", "indexrelname": "events_created_at_idx"}, metricType: dto.MetricType_GAUGE, value: 0.5},
+}
+
+func TestPGBloatCollectorEstimate(t *testing.T) {
+	inst := &instance{dsn: t.Name(), version: semver.MustParse("16.0.0")}
+	c := &PGBloatCollector{log: promslog.NewNopLogger(), mode: config.BloatModeEstimate, limit: 10}
+	testCollectorUpdate(t, c, inst, expectBloatQueries(bloatTableEstimateQuery, bloatIndexEstimateQuery, c.limit), bloatExpected)
+}
+
+func TestPGBloatCollectorExact(t *testing.T) {
+	inst := &instance{dsn: t.Name(), version: semver.MustParse("16.0.0")}
+	c := &PGBloatCollector{log: promslog.NewNopLogger(), mode: config.BloatModeExact, limit: 5}
+	testCollectorUpdate(t, c, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(bloatPgstattupleQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		expectBloatQueries(bloatTableExactQuery, bloatIndexExactQuery, c.limit)(mock)
+	}, bloatExpected)
+}
+
+func TestPGBloatCollectorExactWithoutPgstattuple(t *testing.T) {
+	inst := &instance{dsn: t.Name(), version: semver.MustParse("16.0.0")}
+	c := &PGBloatCollector{log: promslog.NewNopLogger(), mode: config.BloatModeExact, limit: 5}
+	testCollectorUpdate(t, c, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(bloatPgstattupleQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	}, nil)
+}
+
+func TestPGBloatCollectorExactPartitionedIndex(t *testing.T) {
+	inst := &instance{dsn: t.Name(), version: semver.MustParse("16.0.0")}
+	c := &PGBloatCollector{log: promslog.NewNopLogger(), mode: config.BloatModeExact, limit: 5}
+	testCollectorUpdate(t, c, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(bloatPgstattupleQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(bloatTableExactQuery)).WithArgs(c.limit).WillReturnRows(sqlmock.NewRows(bloatTableColumns))
+		// Only the index of the partition is measured, the partitioned
+		// index of the parent has no storage for pgstatindex() to read.
+		mock.ExpectQuery(`AND c\.relkind = 'i'`).WithArgs(c.limit).WillReturnRows(
+			sqlmock.NewRows(bloatIndexColumns).
+				AddRow("orders", "public", "events_2024", "events_2024_created_at_idx", 67108864, 0.25))
+	}, []MetricResult{
+		{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events_2024", "indexrelname": "events_2024_created_at_idx"}, metricType: dto.MetricType_GAUGE, value: 67108864},
+		{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events_2024", "indexrelname": "events_2024_created_at_idx"}, metricType: dto.MetricType_GAUGE, value: 0.25},
+	})
+}
+
+func TestPGBloatCollectorExactBefore95(t *testing.T) {
+	inst := &instance{dsn: t.Name(), version: semver.MustParse("9.4.0")}
+	c := &PGBloatCollector{log: promslog.NewNopLogger(), mode: config.BloatModeExact, limit: 5}
+	testCollectorUpdate(t, c, inst, nil, nil)
+}
+
+func TestPGBloatCollectorCache(t *testing.T) {
+	inst := &instance{dsn: t.Name(), version: semver.MustParse("16.0.0")}
+	c := &PGBloatCollector{log: promslog.NewNopLogger(), mode: config.BloatModeEstimate, limit: 10, cacheTTL: time.Hour}
+	testCollectorUpdate(t, c, inst, expectBloatQueries(bloatTableEstimateQuery, bloatIndexEstimateQuery, c.limit), bloatExpected)
+	// The second scrape is answered from the cache without querying.
+	testCollectorUpdate(t, c, inst, nil, bloatExpected)
+}
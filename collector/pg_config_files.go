@@ -0,0 +1,208 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because reading the configuration files requires
+	//   superuser or explicit grants on pg_file_settings and pg_hba_file_rules
+	registerCollector(configFilesSubsystem, NewPGConfigFilesCollector)
+}
+
+type PGConfigFilesCollector struct {
+	log *slog.Logger
+}
+
+func NewPGConfigFilesCollector(config collectorConfig) (Collector, error) {
+	return &PGConfigFilesCollector{log: config.logger}, nil
+}
+
+var (
+	configFilesLastReloadDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, configFilesSubsystem, "last_reload_time_seconds"),
+		"Time the server configuration files were last loaded, in unixtime",
+		[]string{},
+		prometheus.Labels{},
+	)
+	configFilesPendingRestartDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, configFilesSubsystem, "pending_restart_settings"),
+		"Number of settings changed in the configuration files that require a restart to take effect",
+		[]string{},
+		prometheus.Labels{},
+	)
+	configFilesSettingPendingRestartDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, configFilesSubsystem, "setting_pending_restart"),
+		"Setting changed in the configuration files that requires a restart to take effect",
+		[]string{"name"},
+		prometheus.Labels{},
+	)
+	configFilesErrorLinesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, configFilesSubsystem, "error_lines"),
+		"Number of lines of the configuration file that could not be applied, as reported by the view",
+		[]string{"view", "file"},
+		prometheus.Labels{},
+	)
+
+	configFilesLastReloadQuery = `SELECT EXTRACT(EPOCH FROM pg_conf_load_time()) AS last_reload_time`
+
+	configFilesPendingRestartQuery = `SELECT name FROM pg_catalog.pg_settings WHERE pending_restart ORDER BY name`
+
+	configFilesErrorLinesQuery = `
+	SELECT
+		%s AS file,
+		count(*) FILTER (WHERE error IS NOT NULL) AS error_lines
+	FROM pg_catalog.%s
+	GROUP BY 1
+	ORDER BY 1
+	`
+)
+
+// configFileView is a view that reports the lines of configuration files,
+// with an error for the lines that could not be applied.
+type configFileView struct {
+	view       string
+	minVersion semver.Version
+	file       string
+	// fileBefore16 names the file on versions before PostgreSQL 16 added
+	// the file_name column for the files pulled in by include directives.
+	fileBefore16 string
+}
+
+var configFileViews = []configFileView{
+	{
+		view:       "pg_file_settings",
+		minVersion: semver.MustParse("9.5.0"),
+		file:       "COALESCE(sourcefile, '')",
+	},
+	{
+		view:         "pg_hba_file_rules",
+		minVersion:   semver.MustParse("10.0.0"),
+		file:         "COALESCE(file_name, '')",
+		fileBefore16: "current_setting('hba_file')",
+	},
+	{
+		view:         "pg_ident_file_mappings",
+		minVersion:   semver.MustParse("15.0.0"),
+		file:         "COALESCE(file_name, '')",
+		fileBefore16: "current_setting('ident_file')",
+	},
+}
+
+func (v configFileView) query(version semver.Version) string {
+	file := v.file
+	if v.fileBefore16 != "" && version.LT(semver.MustParse("16.0.0")) {
+		file = v.fileBefore16
+	}
+	return fmt.Sprintf(configFilesErrorLinesQuery, file, v.view)
+}
+
+func (c *PGConfigFilesCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("9.5.0")) {
+		c.log.Warn("config_files collector is not available on PostgreSQL < 9.5.0, skipping")
+		return nil
+	}
+	db := instance.getDB()
+
+	var lastReload sql.NullFloat64
+	if err := db.QueryRowContext(ctx, configFilesLastReloadQuery).Scan(&lastReload); err != nil {
+		return err
+	}
+	if lastReload.Valid {
+		ch <- prometheus.MustNewConstMetric(
+			configFilesLastReloadDesc,
+			prometheus.GaugeValue,
+			lastReload.Float64,
+		)
+	}
+
+	if err := c.updatePendingRestart(ctx, db, ch); err != nil {
+		return err
+	}
+
+	for _, v := range configFileViews {
+		if instance.version.LT(v.minVersion) {
+			continue
+		}
+		if err := c.updateErrorLines(ctx, db, v.view, v.query(instance.version), ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *PGConfigFilesCollector) updatePendingRestart(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric) error {
+	rows, err := db.QueryContext(ctx, configFilesPendingRestartQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	pending := 0
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		pending++
+		ch <- prometheus.MustNewConstMetric(
+			configFilesSettingPendingRestartDesc,
+			prometheus.GaugeValue,
+			1,
+			name,
+		)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		configFilesPendingRestartDesc,
+		prometheus.GaugeValue,
+		float64(pending),
+	)
+	return nil
+}
+
+func (c *PGConfigFilesCollector) updateErrorLines(ctx context.Context, db *sql.DB, view, query string, ch chan<- prometheus.Metric) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var file sql.NullString
+		var errorLines sql.NullFloat64
+		if err := rows.Scan(&file, &errorLines); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(
+			configFilesErrorLinesDesc,
+			prometheus.GaugeValue,
+			errorLines.Float64,
+			view, file.String,
+		)
+	}
+	return rows.Err()
+}
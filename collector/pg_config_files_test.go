@@ -0,0 +1,79 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestPGConfigFilesCollector(t *testing.T) {
+	version := semver.MustParse("16.0.0")
+	inst := &instance{version: version}
+	testCollectorUpdate(t, &PGConfigFilesCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(configFilesLastReloadQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"last_reload_time"}).AddRow(1700000000.5))
+		mock.ExpectQuery(sanitizeQuery(configFilesPendingRestartQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("max_connections").AddRow("shared_buffers"))
+		mock.ExpectQuery(sanitizeQuery(configFileViews[0].query(version))).
+			WillReturnRows(sqlmock.NewRows([]string{"file", "error_lines"}).
+				AddRow("/etc/postgresql/postgresql.conf", 0).
+				AddRow("/var/lib/postgresql/data/postgresql.auto.conf", 1))
+		mock.ExpectQuery(sanitizeQuery(configFileViews[1].query(version))).
+			WillReturnRows(sqlmock.NewRows([]string{"file", "error_lines"}).
+				AddRow("/etc/postgresql/pg_hba.conf", 2))
+		mock.ExpectQuery(sanitizeQuery(configFileViews[2].query(version))).
+			WillReturnRows(sqlmock.NewRows([]string{"file", "error_lines"}).
+				AddRow("/etc/postgresql/pg_ident.conf", 0))
+	}, []MetricResult{
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1700000000.5},
+		{labels: labelMap{"name": "max_connections"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"name": "shared_buffers"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 2},
+		{labels: labelMap{"view": "pg_file_settings", "file": "/etc/postgresql/postgresql.conf"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"view": "pg_file_settings", "file": "/var/lib/postgresql/data/postgresql.auto.conf"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"view": "pg_hba_file_rules", "file": "/etc/postgresql/pg_hba.conf"}, metricType: dto.MetricType_GAUGE, value: 2},
+		{labels: labelMap{"view": "pg_ident_file_mappings", "file": "/etc/postgresql/pg_ident.conf"}, metricType: dto.MetricType_GAUGE, value: 0},
+	})
+}
+
+func TestPGConfigFilesCollectorBefore15(t *testing.T) {
+	version := semver.MustParse("14.0.0")
+	inst := &instance{version: version}
+	testCollectorUpdate(t, &PGConfigFilesCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(configFilesLastReloadQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"last_reload_time"}).AddRow(1700000000))
+		mock.ExpectQuery(sanitizeQuery(configFilesPendingRestartQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectQuery(sanitizeQuery(configFileViews[0].query(version))).
+			WillReturnRows(sqlmock.NewRows([]string{"file", "error_lines"}).
+				AddRow("/etc/postgresql/postgresql.conf", 0))
+		mock.ExpectQuery(sanitizeQuery("SELECT current_setting('hba_file') AS file")).
+			WillReturnRows(sqlmock.NewRows([]string{"file", "error_lines"}).
+				AddRow("/etc/postgresql/pg_hba.conf", 0))
+	}, []MetricResult{
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1700000000},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"view": "pg_file_settings", "file": "/etc/postgresql/postgresql.conf"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"view": "pg_hba_file_rules", "file": "/etc/postgresql/pg_hba.conf"}, metricType: dto.MetricType_GAUGE, value: 0},
+	})
+}
+
+func TestPGConfigFilesCollectorBefore95(t *testing.T) {
+	inst := &instance{version: semver.MustParse("9.4.0")}
+	testCollectorUpdate(t, &PGConfigFilesCollector{log: promslog.NewNopLogger()}, inst, nil, nil)
+}
@@ -0,0 +1,174 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because the cipher and TLS version labels
+	//   multiply the number of series per database and user
+	registerCollector(connectionEncryptionSubsystem, NewPGConnectionEncryptionCollector)
+}
+
+type PGConnectionEncryptionCollector struct {
+	log *slog.Logger
+}
+
+func NewPGConnectionEncryptionCollector(config collectorConfig) (Collector, error) {
+	return &PGConnectionEncryptionCollector{log: config.logger}, nil
+}
+
+var (
+	connectionEncryptionConnectionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, connectionEncryptionSubsystem, "connections"),
+		"Number of client connections by SSL and GSSAPI encryption status",
+		[]string{
+			"datname",
+			"usename",
+			"ssl",
+			"version",
+			"cipher",
+			"gss_authenticated",
+			"gss_encrypted",
+		},
+		prometheus.Labels{},
+	)
+
+	connectionEncryptionQuery = `
+	SELECT
+		a.datname,
+		a.usename,
+		COALESCE(s.ssl, false)::text AS ssl,
+		COALESCE(s.version, '') AS version,
+		COALESCE(s.cipher, '') AS cipher,
+		COALESCE(g.gss_authenticated, false)::text AS gss_authenticated,
+		COALESCE(g.encrypted, false)::text AS gss_encrypted,
+		count(*) AS connections
+	FROM
+		pg_catalog.pg_stat_activity a
+	LEFT JOIN
+		pg_catalog.pg_stat_ssl s ON s.pid = a.pid
+	LEFT JOIN
+		pg_catalog.pg_stat_gssapi g ON g.pid = a.pid
+	WHERE
+		a.backend_type = 'client backend'
+	GROUP BY
+		1, 2, 3, 4, 5, 6, 7
+	`
+
+	// pg_stat_gssapi was added in PostgreSQL 12.
+	connectionEncryptionQueryBefore12 = `
+	SELECT
+		a.datname,
+		a.usename,
+		COALESCE(s.ssl, false)::text AS ssl,
+		COALESCE(s.version, '') AS version,
+		COALESCE(s.cipher, '') AS cipher,
+		'false' AS gss_authenticated,
+		'false' AS gss_encrypted,
+		count(*) AS connections
+	FROM
+		pg_catalog.pg_stat_activity a
+	LEFT JOIN
+		pg_catalog.pg_stat_ssl s ON s.pid = a.pid
+	WHERE
+		a.backend_type = 'client backend'
+	GROUP BY
+		1, 2, 3, 4, 5
+	`
+
+	// backend_type was added in PostgreSQL 10, before that
+	// pg_stat_activity lists client backends and walsenders, and only
+	// client backends are connected to a database.
+	connectionEncryptionQueryBefore10 = `
+	SELECT
+		a.datname,
+		a.usename,
+		COALESCE(s.ssl, false)::text AS ssl,
+		COALESCE(s.version, '') AS version,
+		COALESCE(s.cipher, '') AS cipher,
+		'false' AS gss_authenticated,
+		'false' AS gss_encrypted,
+		count(*) AS connections
+	FROM
+		pg_catalog.pg_stat_activity a
+	LEFT JOIN
+		pg_catalog.pg_stat_ssl s ON s.pid = a.pid
+	WHERE
+		a.datname IS NOT NULL
+	GROUP BY
+		1, 2, 3, 4, 5
+	`
+)
+
+func (c *PGConnectionEncryptionCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("9.5.0")) {
+		c.log.Warn("connection_encryption collector is not available on PostgreSQL < 9.5.0, skipping")
+		return nil
+	}
+
+	query := connectionEncryptionQuery
+	switch {
+	case instance.version.LT(semver.MustParse("10.0.0")):
+		query = connectionEncryptionQueryBefore10
+	case instance.version.LT(semver.MustParse("12.0.0")):
+		query = connectionEncryptionQueryBefore12
+	}
+
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, usename, ssl, version, cipher, gssAuthenticated, gssEncrypted sql.NullString
+		var connections sql.NullFloat64
+		if err := rows.Scan(
+			&datname,
+			&usename,
+			&ssl,
+			&version,
+			&cipher,
+			&gssAuthenticated,
+			&gssEncrypted,
+			&connections,
+		); err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			connectionEncryptionConnectionsDesc,
+			prometheus.GaugeValue,
+			connections.Float64,
+			stringValue(datname),
+			stringValue(usename),
+			stringValue(ssl),
+			stringValue(version),
+			stringValue(cipher),
+			stringValue(gssAuthenticated),
+			stringValue(gssEncrypted),
+		)
+	}
+	return rows.Err()
+}
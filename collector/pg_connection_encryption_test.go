@@ -0,0 +1,78 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+var connectionEncryptionColumns = []string{
+	"datname",
+	"usename",
+	"ssl",
+	"version",
+	"cipher",
+	"gss_authenticated",
+	"gss_encrypted",
+	"connections",
+}
+
+func TestPGConnectionEncryptionCollector(t *testing.T) {
+	rows := sqlmock.NewRows(connectionEncryptionColumns).
+		AddRow("app", "web", "true", "TLSv1.3", "TLS_AES_256_GCM_SHA384", "false", "false", 12).
+		AddRow("app", "batch", "false", "", "", "true", "true", 2).
+		AddRow("postgres", "postgres", "false", "", "", "false", "false", 1)
+
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGConnectionEncryptionCollector{}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(connectionEncryptionQuery)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{"datname": "app", "usename": "web", "ssl": "true", "version": "TLSv1.3", "cipher": "TLS_AES_256_GCM_SHA384", "gss_authenticated": "false", "gss_encrypted": "false"}, metricType: dto.MetricType_GAUGE, value: 12},
+		{labels: labelMap{"datname": "app", "usename": "batch", "ssl": "false", "version": "", "cipher": "", "gss_authenticated": "true", "gss_encrypted": "true"}, metricType: dto.MetricType_GAUGE, value: 2},
+		{labels: labelMap{"datname": "postgres", "usename": "postgres", "ssl": "false", "version": "", "cipher": "", "gss_authenticated": "false", "gss_encrypted": "false"}, metricType: dto.MetricType_GAUGE, value: 1},
+	})
+}
+
+func TestPGConnectionEncryptionCollectorBefore12(t *testing.T) {
+	rows := sqlmock.NewRows(connectionEncryptionColumns).
+		AddRow("app", "web", "true", "TLSv1.2", "ECDHE-RSA-AES256-GCM-SHA384", "false", "false", 3)
+
+	inst := &instance{version: semver.MustParse("11.0.0")}
+	testCollectorUpdate(t, &PGConnectionEncryptionCollector{}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(connectionEncryptionQueryBefore12)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{"datname": "app", "usename": "web", "ssl": "true", "version": "TLSv1.2", "cipher": "ECDHE-RSA-AES256-GCM-SHA384", "gss_authenticated": "false", "gss_encrypted": "false"}, metricType: dto.MetricType_GAUGE, value: 3},
+	})
+}
+
+func TestPGConnectionEncryptionCollectorBefore10(t *testing.T) {
+	rows := sqlmock.NewRows(connectionEncryptionColumns).
+		AddRow("app", "web", "false", "", "", "false", "false", 4)
+
+	inst := &instance{version: semver.MustParse("9.6.0")}
+	testCollectorUpdate(t, &PGConnectionEncryptionCollector{}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(connectionEncryptionQueryBefore10)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{"datname": "app", "usename": "web", "ssl": "false", "version": "", "cipher": "", "gss_authenticated": "false", "gss_encrypted": "false"}, metricType: dto.MetricType_GAUGE, value: 4},
+	})
+}
+
+func TestPGConnectionEncryptionCollectorBefore95(t *testing.T) {
+	inst := &instance{version: semver.MustParse("9.4.0")}
+	testCollectorUpdate(t, &PGConnectionEncryptionCollector{log: promslog.NewNopLogger()}, inst, nil, nil)
+}
@@ -0,0 +1,200 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because the pg_control_* functions require
+	//   superuser or explicit grants
+	registerCollector(controlFileSubsystem, NewPGControlFileCollector)
+}
+
+type PGControlFileCollector struct {
+	log *slog.Logger
+}
+
+func NewPGControlFileCollector(config collectorConfig) (Collector, error) {
+	return &PGControlFileCollector{log: config.logger}, nil
+}
+
+func newControlFileDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, controlFileSubsystem, name),
+		help,
+		[]string{},
+		prometheus.Labels{},
+	)
+}
+
+var (
+	controlFileCheckpointTimeDesc = newControlFileDesc(
+		"checkpoint_time_seconds",
+		"Time of the latest checkpoint, in unixtime",
+	)
+	controlFileCheckpointAgeDesc = newControlFileDesc(
+		"checkpoint_age_seconds",
+		"Time since the latest checkpoint",
+	)
+	controlFileCheckpointLSNDesc = newControlFileDesc(
+		"checkpoint_lsn",
+		"WAL location of the latest checkpoint record",
+	)
+	controlFileRedoLSNDesc = newControlFileDesc(
+		"redo_lsn",
+		"WAL location crash recovery would start replaying from",
+	)
+	controlFileRedoDistanceDesc = newControlFileDesc(
+		"redo_distance_bytes",
+		"Amount of WAL between the redo location of the latest checkpoint and the current WAL location, the WAL crash recovery would have to replay",
+	)
+	controlFileTimelineIDDesc = newControlFileDesc(
+		"timeline_id",
+		"Timeline of the latest checkpoint, increases with every promotion",
+	)
+	controlFilePrevTimelineIDDesc = newControlFileDesc(
+		"prev_timeline_id",
+		"Timeline before the latest checkpoint's timeline",
+	)
+	controlFileNextXIDDesc = newControlFileDesc(
+		"next_xid",
+		"Next transaction ID to assign as of the latest checkpoint, including the epoch",
+	)
+	controlFileOldestXIDDesc = newControlFileDesc(
+		"oldest_xid",
+		"Oldest unfrozen transaction ID as of the latest checkpoint",
+	)
+	controlFileNextMultiXIDDesc = newControlFileDesc(
+		"next_multi_xid",
+		"Next multixact ID to assign as of the latest checkpoint",
+	)
+	controlFileOldestMultiXIDDesc = newControlFileDesc(
+		"oldest_multi_xid",
+		"Oldest unfrozen multixact ID as of the latest checkpoint",
+	)
+	controlFileMinRecoveryEndLSNDesc = newControlFileDesc(
+		"min_recovery_end_lsn",
+		"WAL location a standby has to replay to before it is consistent, 0 on a primary",
+	)
+	controlFileMinRecoveryEndTimelineDesc = newControlFileDesc(
+		"min_recovery_end_timeline",
+		"Timeline of the minimum recovery end location, 0 on a primary",
+	)
+	controlFileSystemInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, controlFileSubsystem, "system_info"),
+		"System identifier of the cluster, shared by a primary and its physical standbys",
+		[]string{"system_identifier"},
+		prometheus.Labels{},
+	)
+
+	// next_xid is formatted as epoch:xid.
+	controlFileQuery = `
+	SELECT
+		EXTRACT(EPOCH FROM c.checkpoint_time) AS checkpoint_time,
+		EXTRACT(EPOCH FROM now() - c.checkpoint_time) AS checkpoint_age_seconds,
+		c.checkpoint_lsn - '0/0' AS checkpoint_lsn,
+		c.redo_lsn - '0/0' AS redo_lsn,
+		CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END - c.redo_lsn AS redo_distance_bytes,
+		c.timeline_id,
+		c.prev_timeline_id,
+		split_part(c.next_xid, ':', 1)::bigint * 4294967296 + split_part(c.next_xid, ':', 2)::bigint AS next_xid,
+		c.oldest_xid::text::bigint AS oldest_xid,
+		c.next_multixact_id::text::bigint AS next_multi_xid,
+		c.oldest_multi_xid::text::bigint AS oldest_multi_xid,
+		r.min_recovery_end_lsn - '0/0' AS min_recovery_end_lsn,
+		r.min_recovery_end_timeline,
+		s.system_identifier::text AS system_identifier
+	FROM
+		pg_control_checkpoint() c,
+		pg_control_recovery() r,
+		pg_control_system() s
+	`
+)
+
+func (c *PGControlFileCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("10.0.0")) {
+		c.log.Warn("control_file collector is not available on PostgreSQL < 10.0.0, skipping")
+		return nil
+	}
+
+	db := instance.getDB()
+	row := db.QueryRowContext(ctx, controlFileQuery)
+
+	var checkpointTime, checkpointAge, checkpointLSN, redoLSN, redoDistance sql.NullFloat64
+	var timelineID, prevTimelineID, nextXID, oldestXID, nextMultiXID, oldestMultiXID sql.NullFloat64
+	var minRecoveryEndLSN, minRecoveryEndTimeline sql.NullFloat64
+	var systemIdentifier sql.NullString
+	if err := row.Scan(
+		&checkpointTime,
+		&checkpointAge,
+		&checkpointLSN,
+		&redoLSN,
+		&redoDistance,
+		&timelineID,
+		&prevTimelineID,
+		&nextXID,
+		&oldestXID,
+		&nextMultiXID,
+		&oldestMultiXID,
+		&minRecoveryEndLSN,
+		&minRecoveryEndTimeline,
+		&systemIdentifier,
+	); err != nil {
+		return err
+	}
+
+	for _, m := range []struct {
+		desc  *prometheus.Desc
+		value sql.NullFloat64
+	}{
+		{controlFileCheckpointTimeDesc, checkpointTime},
+		{controlFileCheckpointAgeDesc, checkpointAge},
+		{controlFileCheckpointLSNDesc, checkpointLSN},
+		{controlFileRedoLSNDesc, redoLSN},
+		{controlFileRedoDistanceDesc, redoDistance},
+		{controlFileTimelineIDDesc, timelineID},
+		{controlFilePrevTimelineIDDesc, prevTimelineID},
+		{controlFileNextXIDDesc, nextXID},
+		{controlFileOldestXIDDesc, oldestXID},
+		{controlFileNextMultiXIDDesc, nextMultiXID},
+		{controlFileOldestMultiXIDDesc, oldestMultiXID},
+		{controlFileMinRecoveryEndLSNDesc, minRecoveryEndLSN},
+		{controlFileMinRecoveryEndTimelineDesc, minRecoveryEndTimeline},
+	} {
+		// The replay location is NULL on a standby that has not replayed
+		// any WAL yet.
+		if !m.value.Valid {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, m.value.Float64)
+	}
+
+	if systemIdentifier.Valid {
+		ch <- prometheus.MustNewConstMetric(
+			controlFileSystemInfoDesc,
+			prometheus.GaugeValue,
+			1,
+			systemIdentifier.String,
+		)
+	}
+	return nil
+}
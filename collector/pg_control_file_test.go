@@ -0,0 +1,98 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+var controlFileColumns = []string{
+	"checkpoint_time",
+	"checkpoint_age_seconds",
+	"checkpoint_lsn",
+	"redo_lsn",
+	"redo_distance_bytes",
+	"timeline_id",
+	"prev_timeline_id",
+	"next_xid",
+	"oldest_xid",
+	"next_multi_xid",
+	"oldest_multi_xid",
+	"min_recovery_end_lsn",
+	"min_recovery_end_timeline",
+	"system_identifier",
+}
+
+func TestPGControlFileCollector(t *testing.T) {
+	rows := sqlmock.NewRows(controlFileColumns).AddRow(
+		1700000000, 120.5, 50331688, 50331648, 16777216,
+		3, 2, 4294968296, 726, 1, 1,
+		0, 0, "7301234567890123456")
+
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGControlFileCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(controlFileQuery)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1700000000},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 120.5},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 50331688},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 50331648},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 16777216},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 3},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 2},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 4294968296},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 726},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"system_identifier": "7301234567890123456"}, metricType: dto.MetricType_GAUGE, value: 1},
+	})
+}
+
+func TestPGControlFileCollectorStandbyWithoutReplay(t *testing.T) {
+	rows := sqlmock.NewRows(controlFileColumns).AddRow(
+		1700000000, 30, 50331688, 50331648, nil,
+		3, 3, 4294968296, 726, 1, 1,
+		67108864, 3, "7301234567890123456")
+
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGControlFileCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(controlFileQuery)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1700000000},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 30},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 50331688},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 50331648},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 3},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 3},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 4294968296},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 726},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 67108864},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 3},
+		{labels: labelMap{"system_identifier": "7301234567890123456"}, metricType: dto.MetricType_GAUGE, value: 1},
+	})
+}
+
+func TestPGControlFileCollectorBefore10(t *testing.T) {
+	inst := &instance{version: semver.MustParse("9.6.0")}
+	testCollectorUpdate(t, &PGControlFileCollector{log: promslog.NewNopLogger()}, inst, nil, nil)
+}
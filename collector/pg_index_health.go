@@ -0,0 +1,338 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because it compares every index of the database
+	//   with the other indexes of its table on each scrape
+	registerCollector(indexHealthSubsystem, NewPGIndexHealthCollector)
+}
+
+func defaultIndexHealthConfig() config.IndexHealthConfig {
+	return config.IndexHealthConfig{
+		IncludeIndexes: config.DefaultIndexHealthIncludeIndexes,
+		IndexLimit:     config.DefaultIndexHealthIndexLimit,
+	}
+}
+
+func withIndexHealthDefaults(c config.IndexHealthConfig) config.IndexHealthConfig {
+	if c.IndexLimit == 0 {
+		c.IndexLimit = config.DefaultIndexHealthIndexLimit
+	}
+	return c
+}
+
+type PGIndexHealthCollector struct {
+	log            *slog.Logger
+	includeIndexes bool
+	indexLimit     uint
+}
+
+func NewPGIndexHealthCollector(collectorCfg collectorConfig) (Collector, error) {
+	cfg := withIndexHealthDefaults(collectorCfg.indexHealth)
+	return &PGIndexHealthCollector{
+		log:            collectorCfg.logger,
+		includeIndexes: cfg.IncludeIndexes,
+		indexLimit:     cfg.IndexLimit,
+	}, nil
+}
+
+// indexHealthUnindexedForeignKey is the problem of foreign keys, which are
+// reported apart from the indexes.
+const indexHealthUnindexedForeignKey = "unindexed_foreign_key"
+
+var (
+	indexHealthIndexes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, indexHealthSubsystem, "indexes"),
+		"Number of indexes with the problem: unused (not scanned since statistics were reset), invalid, or duplicate (made redundant by another index on the same leading columns)",
+		[]string{"datname", "problem"},
+		prometheus.Labels{},
+	)
+	indexHealthSizeBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, indexHealthSubsystem, "size_bytes"),
+		"Disk space used by the indexes with the problem",
+		[]string{"datname", "problem"},
+		prometheus.Labels{},
+	)
+	indexHealthUnindexedForeignKeys = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, indexHealthSubsystem, "unindexed_foreign_keys"),
+		"Number of foreign keys without an index on their columns",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	indexHealthIndexSizeBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, indexHealthSubsystem, "index_size_bytes"),
+		"Disk space used by the index with the problem",
+		[]string{"datname", "schemaname", "relname", "indexrelname", "problem"},
+		prometheus.Labels{},
+	)
+	indexHealthUnindexedForeignKeyInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, indexHealthSubsystem, "unindexed_foreign_key_info"),
+		"Foreign key without an index on its columns",
+		[]string{"datname", "schemaname", "relname", "conname"},
+		prometheus.Labels{},
+	)
+
+	// Indexes backing a constraint are never reported as unused, since
+	// they are needed even if never scanned. An index is a duplicate of
+	// another with the same expressions and predicate when both have the
+	// same columns and operator classes, or when it is a non-unique btree
+	// index on leading columns of the other. Of two identical indexes, the
+	// one kept is unique over non-unique, then the primary key, then the
+	// oldest. A foreign key is indexed by a btree index whose leading key
+	// columns, not counting INCLUDE columns, are those of the key, or by a
+	// hash index on its single column. Foreign keys cloned to partitions are
+	// only counted on the partitioned table.
+	indexHealthIssuesQuery = `
+	WITH indexes AS (
+		SELECT
+			i.indexrelid,
+			i.indrelid,
+			i.indisvalid,
+			i.indisunique,
+			i.indisprimary,
+			i.indkey::text AS indkey,
+			i.indclass::text AS indclass,
+			pg_get_expr(i.indexprs, i.indrelid) AS indexprs,
+			pg_get_expr(i.indpred, i.indrelid) AS indpred,
+			am.amname,
+			n.nspname AS schemaname,
+			t.relname,
+			c.relname AS indexrelname
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class c ON c.oid = i.indexrelid
+		JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_am am ON am.oid = c.relam
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND n.nspname !~ '^pg_toast'
+	), issues AS (
+		SELECT
+			'unused' AS problem,
+			x.schemaname,
+			x.relname,
+			x.indexrelname AS name,
+			pg_relation_size(x.indexrelid) AS size_bytes
+		FROM indexes x
+		JOIN pg_catalog.pg_stat_all_indexes s ON s.indexrelid = x.indexrelid
+		WHERE s.idx_scan = 0
+			AND x.indisvalid
+			AND NOT x.indisunique
+			AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint k WHERE k.conindid = x.indexrelid)
+		UNION ALL
+		SELECT
+			'invalid',
+			x.schemaname,
+			x.relname,
+			x.indexrelname,
+			pg_relation_size(x.indexrelid)
+		FROM indexes x
+		WHERE NOT x.indisvalid
+		UNION ALL
+		SELECT
+			'duplicate',
+			a.schemaname,
+			a.relname,
+			a.indexrelname,
+			pg_relation_size(a.indexrelid)
+		FROM indexes a
+		WHERE a.indisvalid
+			AND EXISTS (
+				SELECT 1
+				FROM indexes b
+				WHERE b.indrelid = a.indrelid
+					AND b.indexrelid <> a.indexrelid
+					AND b.indisvalid
+					AND b.amname = a.amname
+					AND b.indexprs IS NOT DISTINCT FROM a.indexprs
+					AND b.indpred IS NOT DISTINCT FROM a.indpred
+					AND (
+						(
+							b.indkey = a.indkey
+							AND b.indclass = a.indclass
+							AND (
+								(b.indisunique AND NOT a.indisunique)
+								OR (
+									b.indisunique = a.indisunique
+									AND NOT a.indisprimary
+									AND (b.indisprimary OR b.indexrelid < a.indexrelid)
+								)
+							)
+						)
+						OR (
+							a.amname = 'btree'
+							AND NOT a.indisunique
+							AND b.indkey LIKE a.indkey || ' %'
+							AND b.indclass LIKE a.indclass || ' %'
+						)
+					)
+			)
+		UNION ALL
+		SELECT
+			'unindexed_foreign_key',
+			n.nspname,
+			t.relname,
+			k.conname,
+			NULL
+		FROM pg_catalog.pg_constraint k
+		JOIN pg_catalog.pg_class t ON t.oid = k.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+		WHERE k.contype = 'f'
+			AND k.conparentid = 0
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND NOT EXISTS (
+				SELECT 1
+				FROM pg_catalog.pg_index i
+				JOIN pg_catalog.pg_class c ON c.oid = i.indexrelid
+				JOIN pg_catalog.pg_am am ON am.oid = c.relam
+				WHERE i.indrelid = k.conrelid
+					AND i.indisvalid
+					AND i.indpred IS NULL
+					AND (am.amname = 'btree' OR (am.amname = 'hash' AND array_length(k.conkey, 1) = 1))
+					AND array_length(k.conkey, 1) <= i.indnkeyatts
+					AND (string_to_array(i.indkey::text, ' ')::int2[])[1:array_length(k.conkey, 1)] @> k.conkey
+			)
+	)
+	`
+
+	indexHealthQuery = indexHealthIssuesQuery + `
+	SELECT
+		current_database() AS datname,
+		p.problem,
+		count(i.problem) AS count,
+		sum(i.size_bytes) AS size_bytes
+	FROM (VALUES ('duplicate'), ('invalid'), ('unindexed_foreign_key'), ('unused')) AS p (problem)
+	LEFT JOIN issues i ON i.problem = p.problem
+	GROUP BY p.problem
+	ORDER BY p.problem
+	`
+
+	indexHealthDetailQuery = indexHealthIssuesQuery + `
+	SELECT
+		current_database() AS datname,
+		problem,
+		schemaname,
+		relname,
+		name,
+		size_bytes
+	FROM (
+		SELECT
+			i.*,
+			row_number() OVER (PARTITION BY i.problem ORDER BY i.size_bytes DESC NULLS LAST, i.schemaname, i.relname, i.name) AS rank
+		FROM issues i
+	) AS ranked
+	WHERE rank <= $1
+	ORDER BY problem, rank
+	`
+)
+
+func (c *PGIndexHealthCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("11.0.0")) {
+		c.log.Warn("index_health collector is not available on PostgreSQL < 11.0.0, skipping")
+		return nil
+	}
+
+	if err := c.updateProblems(ctx, instance, ch); err != nil {
+		return err
+	}
+	if !c.includeIndexes {
+		return nil
+	}
+	return c.updateDetails(ctx, instance, ch)
+}
+
+func (c *PGIndexHealthCollector) updateProblems(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, indexHealthQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, problem string
+		var count, size sql.NullFloat64
+		if err := rows.Scan(&datname, &problem, &count, &size); err != nil {
+			return err
+		}
+
+		if problem == indexHealthUnindexedForeignKey {
+			ch <- prometheus.MustNewConstMetric(
+				indexHealthUnindexedForeignKeys,
+				prometheus.GaugeValue,
+				count.Float64,
+				datname,
+			)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			indexHealthIndexes,
+			prometheus.GaugeValue,
+			count.Float64,
+			datname, problem,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			indexHealthSizeBytes,
+			prometheus.GaugeValue,
+			size.Float64,
+			datname, problem,
+		)
+	}
+	return rows.Err()
+}
+
+func (c *PGIndexHealthCollector) updateDetails(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, indexHealthDetailQuery, c.indexLimit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, problem, schemaname, relname, name string
+		var size sql.NullFloat64
+		if err := rows.Scan(&datname, &problem, &schemaname, &relname, &name, &size); err != nil {
+			return err
+		}
+
+		if problem == indexHealthUnindexedForeignKey {
+			ch <- prometheus.MustNewConstMetric(
+				indexHealthUnindexedForeignKeyInfo,
+				prometheus.GaugeValue,
+				1,
+				datname, schemaname, relname, name,
+			)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			indexHealthIndexSizeBytes,
+			prometheus.GaugeValue,
+			size.Float64,
+			datname, schemaname, relname, name, problem,
+		)
+	}
+	return rows.Err()
+}
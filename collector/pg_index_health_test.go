@@ -0,0 +1,72 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+func indexHealthRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"datname", "problem", "count", "size_bytes"}).
+		AddRow("orders", "duplicate", 1, 16384).
+		AddRow("orders", "invalid", 0, nil).
+		AddRow("orders", "unindexed_foreign_key", 2, nil).
+		AddRow("orders", "unused", 3, 1073774592)
+}
+
+var indexHealthExpected = []MetricResult{
+	{labels: labelMap{"datname": "orders", "problem": "duplicate"}, metricType: dto.MetricType_GAUGE, value: 1},
+	{labels: labelMap{"datname": "orders", "problem": "duplicate"}, metricType: dto.MetricType_GAUGE, value: 16384},
+	{labels: labelMap{"datname": "orders", "problem": "invalid"}, metricType: dto.MetricType_GAUGE, value: 0},
+	{labels: labelMap{"datname": "orders", "problem": "invalid"}, metricType: dto.MetricType_GAUGE, value: 0},
+	{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 2},
+	{labels: labelMap{"datname": "orders", "problem": "unused"}, metricType: dto.MetricType_GAUGE, value: 3},
+	{labels: labelMap{"datname": "orders", "problem": "unused"}, metricType: dto.MetricType_GAUGE, value: 1073774592},
+}
+
+func TestPGIndexHealthCollector(t *testing.T) {
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGIndexHealthCollector{log: promslog.NewNopLogger(), indexLimit: 10}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(indexHealthQuery)).WillReturnRows(indexHealthRows())
+	}, indexHealthExpected)
+}
+
+func TestPGIndexHealthCollectorIncludeIndexes(t *testing.T) {
+	detailRows := sqlmock.NewRows([]string{"datname", "problem", "schemaname", "relname", "name", "size_bytes"}).
+		AddRow("orders", "duplicate", "public", "events", "events_customer_id_idx", 16384).
+		AddRow("orders", "unindexed_foreign_key", "public", "events", "events_customer_id_fkey", nil).
+		AddRow("orders", "unused", "public", "events", "events_payload_idx", 1073741824)
+
+	expected := append(append([]MetricResult{}, indexHealthExpected...),
+		MetricResult{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events", "indexrelname": "events_customer_id_idx", "problem": "duplicate"}, metricType: dto.MetricType_GAUGE, value: 16384},
+		MetricResult{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events", "conname": "events_customer_id_fkey"}, metricType: dto.MetricType_GAUGE, value: 1},
+		MetricResult{labels: labelMap{"datname": "orders", "schemaname": "public", "relname": "events", "indexrelname": "events_payload_idx", "problem": "unused"}, metricType: dto.MetricType_GAUGE, value: 1073741824},
+	)
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	c := &PGIndexHealthCollector{log: promslog.NewNopLogger(), includeIndexes: true, indexLimit: 3}
+	testCollectorUpdate(t, c, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(indexHealthQuery)).WillReturnRows(indexHealthRows())
+		mock.ExpectQuery(regexp.QuoteMeta(indexHealthDetailQuery)).WithArgs(c.indexLimit).WillReturnRows(detailRows)
+	}, expected)
+}
+
+func TestPGIndexHealthCollectorBefore11(t *testing.T) {
+	inst := &instance{version: semver.MustParse("10.0.0")}
+	testCollectorUpdate(t, &PGIndexHealthCollector{log: promslog.NewNopLogger(), indexLimit: 10}, inst, nil, nil)
+}
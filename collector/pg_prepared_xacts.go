@@ -0,0 +1,207 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/prometheus-community/postgres_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(preparedXactsSubsystem, NewPGPreparedXactsCollector)
+}
+
+func defaultPreparedXactsConfig() config.PreparedXactsConfig {
+	return config.PreparedXactsConfig{
+		IncludeGID: config.DefaultPreparedXactsIncludeGID,
+		GIDLimit:   config.DefaultPreparedXactsGIDLimit,
+	}
+}
+
+func withPreparedXactsDefaults(c config.PreparedXactsConfig) config.PreparedXactsConfig {
+	if c.GIDLimit == 0 {
+		c.GIDLimit = config.DefaultPreparedXactsGIDLimit
+	}
+	return c
+}
+
+type PGPreparedXactsCollector struct {
+	log        *slog.Logger
+	includeGID bool
+	gidLimit   uint
+}
+
+func NewPGPreparedXactsCollector(collectorCfg collectorConfig) (Collector, error) {
+	cfg := withPreparedXactsDefaults(collectorCfg.preparedXacts)
+	return &PGPreparedXactsCollector{
+		log:        collectorCfg.logger,
+		includeGID: cfg.IncludeGID,
+		gidLimit:   cfg.GIDLimit,
+	}, nil
+}
+
+var (
+	preparedXactsCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, preparedXactsSubsystem, "count"),
+		"Number of transactions prepared for two-phase commit",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	preparedXactsOldestAgeSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, preparedXactsSubsystem, "oldest_age_seconds"),
+		"Time since the oldest prepared transaction was prepared",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	preparedXactsOldestXIDAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, preparedXactsSubsystem, "oldest_xid_age"),
+		"Age in transaction IDs of the oldest prepared transaction, which holds back the xmin horizon",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	preparedXactsTransactionAgeSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, preparedXactsSubsystem, "transaction_age_seconds"),
+		"Time since the prepared transaction was prepared",
+		[]string{"datname", "gid", "owner"},
+		prometheus.Labels{},
+	)
+	preparedXactsTransactionXIDAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, preparedXactsSubsystem, "transaction_xid_age"),
+		"Age in transaction IDs of the prepared transaction",
+		[]string{"datname", "gid", "owner"},
+		prometheus.Labels{},
+	)
+
+	// Databases without prepared transactions report a count of 0. Databases
+	// that do not allow connections are included, a transaction prepared
+	// before connections were disallowed still holds back the xmin horizon.
+	preparedXactsQuery = `
+	SELECT
+		d.datname,
+		count(p.gid) AS count,
+		COALESCE(EXTRACT(EPOCH FROM now() - min(p.prepared)), 0) AS oldest_age_seconds,
+		COALESCE(max(age(p.transaction)), 0) AS oldest_xid_age
+	FROM
+		pg_catalog.pg_database d
+	LEFT JOIN
+		pg_catalog.pg_prepared_xacts p ON p.database = d.datname
+	GROUP BY
+		d.datname
+	`
+
+	preparedXactsGIDQuery = `
+	SELECT
+		database,
+		gid,
+		owner,
+		EXTRACT(EPOCH FROM now() - prepared) AS age_seconds,
+		age(transaction) AS xid_age
+	FROM
+		pg_catalog.pg_prepared_xacts
+	ORDER BY
+		prepared
+	LIMIT %d
+	`
+)
+
+func (c *PGPreparedXactsCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, preparedXactsQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname sql.NullString
+		var count, oldestAgeSeconds, oldestXIDAge sql.NullFloat64
+		if err := rows.Scan(&datname, &count, &oldestAgeSeconds, &oldestXIDAge); err != nil {
+			return err
+		}
+		if !datname.Valid {
+			c.log.Debug("Skipping database with NULL name")
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			preparedXactsCount,
+			prometheus.GaugeValue,
+			count.Float64,
+			datname.String,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			preparedXactsOldestAgeSeconds,
+			prometheus.GaugeValue,
+			oldestAgeSeconds.Float64,
+			datname.String,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			preparedXactsOldestXIDAge,
+			prometheus.GaugeValue,
+			oldestXIDAge.Float64,
+			datname.String,
+		)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !c.includeGID {
+		return nil
+	}
+	return c.updateGIDs(ctx, instance, ch)
+}
+
+// updateGIDs reports the oldest prepared transactions with their gid. The
+// number of transactions is limited, as every gid is a new time series.
+func (c *PGPreparedXactsCollector) updateGIDs(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(preparedXactsGIDQuery, c.gidLimit))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, gid, owner sql.NullString
+		var ageSeconds, xidAge sql.NullFloat64
+		if err := rows.Scan(&datname, &gid, &owner, &ageSeconds, &xidAge); err != nil {
+			return err
+		}
+		labels := []string{datname.String, gid.String, owner.String}
+
+		if ageSeconds.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				preparedXactsTransactionAgeSeconds,
+				prometheus.GaugeValue,
+				ageSeconds.Float64,
+				labels...,
+			)
+		}
+		if xidAge.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				preparedXactsTransactionXIDAge,
+				prometheus.GaugeValue,
+				xidAge.Float64,
+				labels...,
+			)
+		}
+	}
+	return rows.Err()
+}
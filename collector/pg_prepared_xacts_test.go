@@ -0,0 +1,115 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestPGPreparedXactsCollector(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening a stub db connection: %s", err)
+	}
+	defer db.Close()
+
+	inst := &instance{db: db}
+
+	rows := sqlmock.NewRows([]string{"datname", "count", "oldest_age_seconds", "oldest_xid_age"}).
+		AddRow("orders", 2, 86400.5, 1500000).
+		AddRow("postgres", 0, 0, 0)
+	mock.ExpectQuery(sanitizeQuery(preparedXactsQuery)).WillReturnRows(rows)
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		c := PGPreparedXactsCollector{}
+
+		if err := c.Update(context.Background(), inst, ch); err != nil {
+			t.Errorf("Error calling PGPreparedXactsCollector.Update: %s", err)
+		}
+	}()
+
+	expected := []MetricResult{
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 2},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 86400.5},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 1500000},
+		{labels: labelMap{"datname": "postgres"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"datname": "postgres"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"datname": "postgres"}, metricType: dto.MetricType_GAUGE, value: 0},
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range expected {
+			m := readMetric(<-ch)
+			convey.So(expect, convey.ShouldResemble, m)
+		}
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled exceptions: %s", err)
+	}
+}
+
+func TestPGPreparedXactsCollectorWithGID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening a stub db connection: %s", err)
+	}
+	defer db.Close()
+
+	inst := &instance{db: db}
+
+	rows := sqlmock.NewRows([]string{"datname", "count", "oldest_age_seconds", "oldest_xid_age"}).
+		AddRow("orders", 1, 600, 42)
+	mock.ExpectQuery(sanitizeQuery(preparedXactsQuery)).WillReturnRows(rows)
+
+	gidRows := sqlmock.NewRows([]string{"database", "gid", "owner", "age_seconds", "xid_age"}).
+		AddRow("orders", "xa-1f2e", "app", 600, 42)
+	mock.ExpectQuery(sanitizeQuery(fmt.Sprintf(preparedXactsGIDQuery, 3))).WillReturnRows(gidRows)
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		c := PGPreparedXactsCollector{includeGID: true, gidLimit: 3}
+
+		if err := c.Update(context.Background(), inst, ch); err != nil {
+			t.Errorf("Error calling PGPreparedXactsCollector.Update: %s", err)
+		}
+	}()
+
+	gid := labelMap{"datname": "orders", "gid": "xa-1f2e", "owner": "app"}
+	expected := []MetricResult{
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 600},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 42},
+		{labels: gid, metricType: dto.MetricType_GAUGE, value: 600},
+		{labels: gid, metricType: dto.MetricType_GAUGE, value: 42},
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range expected {
+			m := readMetric(<-ch)
+			convey.So(expect, convey.ShouldResemble, m)
+		}
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled exceptions: %s", err)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because last_value is only visible for the
+	//   sequences the role has USAGE or SELECT on
+	registerCollector(sequencesSubsystem, NewPGSequencesCollector)
+}
+
+func defaultSequencesConfig() config.SequencesConfig {
+	return config.SequencesConfig{
+		Threshold: config.DefaultSequencesThreshold,
+		Limit:     config.DefaultSequencesLimit,
+	}
+}
+
+func withSequencesDefaults(c config.SequencesConfig) config.SequencesConfig {
+	if c.Limit == 0 {
+		c.Limit = config.DefaultSequencesLimit
+	}
+	return c
+}
+
+type PGSequencesCollector struct {
+	log       *slog.Logger
+	threshold float64
+	limit     uint
+}
+
+func NewPGSequencesCollector(collectorCfg collectorConfig) (Collector, error) {
+	cfg := withSequencesDefaults(collectorCfg.sequences)
+	return &PGSequencesCollector{
+		log:       collectorCfg.logger,
+		threshold: cfg.Threshold,
+		limit:     cfg.Limit,
+	}, nil
+}
+
+var (
+	sequencesLabels = []string{"datname", "schemaname", "sequencename", "data_type", "column_type"}
+
+	sequencesMaxUsedRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, sequencesSubsystem, "max_used_ratio"),
+		"Largest fraction of its range used by a sequence of the database",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	sequencesOverThreshold = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, sequencesSubsystem, "over_threshold"),
+		"Number of sequences of the database that have used at least the threshold fraction of their range",
+		[]string{"datname"},
+		prometheus.Labels{},
+	)
+	sequencesUsedRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, sequencesSubsystem, "used_ratio"),
+		"Fraction of its range the sequence has used, as last_value over the limit of its data type, max_value and the columns it feeds",
+		sequencesLabels,
+		prometheus.Labels{},
+	)
+	sequencesLastValue = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, sequencesSubsystem, "last_value"),
+		"Last value returned by the sequence",
+		sequencesLabels,
+		prometheus.Labels{},
+	)
+	sequencesLimitValue = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, sequencesSubsystem, "limit_value"),
+		"Value at which the sequence is exhausted, max_value or min_value for descending sequences, narrowed to the range of the columns it feeds",
+		sequencesLabels,
+		prometheus.Labels{},
+	)
+
+	// The columns a sequence feeds are found through the dependencies of
+	// serial and identity columns on their sequence, and of column defaults
+	// calling nextval() on it. The narrowest of them sets the limit, and is
+	// reported as column_type. last_value is NULL for sequences never used
+	// or not readable by the role, which are left out.
+	sequencesCTE = `
+	WITH columns AS (
+		SELECT d.objid AS seqoid, d.refobjid AS relid, d.refobjsubid AS attnum
+		FROM pg_catalog.pg_depend d
+		WHERE d.classid = 'pg_catalog.pg_class'::regclass
+			AND d.refclassid = 'pg_catalog.pg_class'::regclass
+			AND d.deptype IN ('a', 'i')
+			AND d.refobjsubid > 0
+		UNION
+		SELECT d.refobjid, ad.adrelid, ad.adnum
+		FROM pg_catalog.pg_depend d
+		JOIN pg_catalog.pg_attrdef ad ON ad.oid = d.objid
+		WHERE d.classid = 'pg_catalog.pg_attrdef'::regclass
+			AND d.refclassid = 'pg_catalog.pg_class'::regclass
+	), column_limits AS (
+		SELECT DISTINCT ON (c.seqoid)
+			c.seqoid,
+			format_type(a.atttypid, NULL) AS column_type,
+			l.min_value,
+			l.max_value
+		FROM columns c
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.relid AND a.attnum = c.attnum
+		JOIN (VALUES
+			('pg_catalog.int2'::regtype, -32768::numeric, 32767::numeric),
+			('pg_catalog.int4'::regtype, -2147483648::numeric, 2147483647::numeric),
+			('pg_catalog.int8'::regtype, -9223372036854775808::numeric, 9223372036854775807::numeric)
+		) AS l (typid, min_value, max_value) ON l.typid = a.atttypid
+		ORDER BY c.seqoid, l.max_value
+	), sequences AS (
+		SELECT
+			s.schemaname,
+			s.sequencename,
+			s.data_type::text AS data_type,
+			COALESCE(l.column_type, '') AS column_type,
+			s.last_value,
+			CASE
+				WHEN s.increment_by > 0 THEN LEAST(s.max_value, l.max_value)
+				ELSE GREATEST(s.min_value, l.min_value)
+			END AS limit_value
+		FROM pg_catalog.pg_sequences s
+		JOIN pg_catalog.pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_catalog.pg_class c ON c.relnamespace = n.oid AND c.relname = s.sequencename
+		LEFT JOIN column_limits l ON l.seqoid = c.oid
+		WHERE s.last_value IS NOT NULL
+	), ratios AS (
+		SELECT
+			*,
+			GREATEST(last_value::float8 / NULLIF(limit_value, 0)::float8, 0) AS used_ratio
+		FROM sequences
+	)
+	`
+
+	sequencesQuery = sequencesCTE + `
+	SELECT
+		current_database() AS datname,
+		COALESCE(max(used_ratio), 0) AS max_used_ratio,
+		count(*) FILTER (WHERE used_ratio >= $1) AS over_threshold
+	FROM ratios
+	`
+
+	sequencesDetailQuery = sequencesCTE + `
+	SELECT
+		current_database() AS datname,
+		schemaname,
+		sequencename,
+		data_type,
+		column_type,
+		last_value,
+		limit_value,
+		used_ratio
+	FROM ratios
+	WHERE used_ratio >= $1
+	ORDER BY used_ratio DESC, schemaname, sequencename
+	LIMIT $2
+	`
+)
+
+func (c *PGSequencesCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("10.0.0")) {
+		c.log.Warn("sequences collector is not available on PostgreSQL < 10.0.0, skipping")
+		return nil
+	}
+
+	db := instance.getDB()
+	var datname string
+	var maxUsedRatio, overThreshold sql.NullFloat64
+	if err := db.QueryRowContext(ctx, sequencesQuery, c.threshold).Scan(&datname, &maxUsedRatio, &overThreshold); err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(
+		sequencesMaxUsedRatio,
+		prometheus.GaugeValue,
+		maxUsedRatio.Float64,
+		datname,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		sequencesOverThreshold,
+		prometheus.GaugeValue,
+		overThreshold.Float64,
+		datname,
+	)
+
+	rows, err := db.QueryContext(ctx, sequencesDetailQuery, c.threshold, c.limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, schemaname, sequencename, dataType, columnType string
+		var lastValue, limitValue, usedRatio sql.NullFloat64
+		if err := rows.Scan(&datname, &schemaname, &sequencename, &dataType, &columnType, &lastValue, &limitValue, &usedRatio); err != nil {
+			return err
+		}
+
+		labels := []string{datname, schemaname, sequencename, dataType, columnType}
+		ch <- prometheus.MustNewConstMetric(
+			sequencesUsedRatio,
+			prometheus.GaugeValue,
+			usedRatio.Float64,
+			labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			sequencesLastValue,
+			prometheus.GaugeValue,
+			lastValue.Float64,
+			labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			sequencesLimitValue,
+			prometheus.GaugeValue,
+			limitValue.Float64,
+			labels...,
+		)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,74 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+var sequencesDetailColumns = []string{
+	"datname", "schemaname", "sequencename", "data_type", "column_type", "last_value", "limit_value", "used_ratio",
+}
+
+func TestPGSequencesCollector(t *testing.T) {
+	rows := sqlmock.NewRows([]string{"datname", "max_used_ratio", "over_threshold"}).
+		AddRow("orders", 0.8, 2)
+	// An int8 sequence feeding an int4 column is limited by the column.
+	detailRows := sqlmock.NewRows(sequencesDetailColumns).
+		AddRow("orders", "public", "events_id_seq", "bigint", "integer", 1717986918, 2147483647, 0.8).
+		AddRow("orders", "public", "invoices_id_seq", "integer", "integer", 1288490188, 2147483647, 0.6)
+
+	events := labelMap{"datname": "orders", "schemaname": "public", "sequencename": "events_id_seq", "data_type": "bigint", "column_type": "integer"}
+	invoices := labelMap{"datname": "orders", "schemaname": "public", "sequencename": "invoices_id_seq", "data_type": "integer", "column_type": "integer"}
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	c := &PGSequencesCollector{log: promslog.NewNopLogger(), threshold: 0.5, limit: 10}
+	testCollectorUpdate(t, c, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(sequencesQuery)).WithArgs(c.threshold).WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta(sequencesDetailQuery)).WithArgs(c.threshold, c.limit).WillReturnRows(detailRows)
+	}, []MetricResult{
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 0.8},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 2},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 0.8},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 1717986918},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 2147483647},
+		{labels: invoices, metricType: dto.MetricType_GAUGE, value: 0.6},
+		{labels: invoices, metricType: dto.MetricType_GAUGE, value: 1288490188},
+		{labels: invoices, metricType: dto.MetricType_GAUGE, value: 2147483647},
+	})
+}
+
+func TestPGSequencesCollectorUnderThreshold(t *testing.T) {
+	rows := sqlmock.NewRows([]string{"datname", "max_used_ratio", "over_threshold"}).
+		AddRow("orders", 0.01, 0)
+
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	c := &PGSequencesCollector{log: promslog.NewNopLogger(), threshold: 0.5, limit: 10}
+	testCollectorUpdate(t, c, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(sequencesQuery)).WithArgs(c.threshold).WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta(sequencesDetailQuery)).WithArgs(c.threshold, c.limit).WillReturnRows(sqlmock.NewRows(sequencesDetailColumns))
+	}, []MetricResult{
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 0.01},
+		{labels: labelMap{"datname": "orders"}, metricType: dto.MetricType_GAUGE, value: 0},
+	})
+}
+
+func TestPGSequencesCollectorBefore10(t *testing.T) {
+	inst := &instance{version: semver.MustParse("9.6.0")}
+	testCollectorUpdate(t, &PGSequencesCollector{log: promslog.NewNopLogger(), threshold: 0.5, limit: 10}, inst, nil, nil)
+}
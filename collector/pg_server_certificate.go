@@ -0,0 +1,212 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus-community/postgres_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because the exporter then verifies the server
+	//   certificate itself instead of leaving it to the driver
+	registerCollector(serverCertificateSubsystem, NewPGServerCertificateCollector)
+}
+
+// Reasons for the verification failures counter.
+const (
+	certificateReasonExpired          = "expired"
+	certificateReasonNotYetValid      = "not_yet_valid"
+	certificateReasonUnknownAuthority = "unknown_authority"
+	certificateReasonHostnameMismatch = "hostname_mismatch"
+	certificateReasonInvalid          = "invalid"
+	certificateReasonSSLNotSupported  = "ssl_not_supported"
+	certificateReasonHandshakeFailed  = "handshake_failed"
+)
+
+// certificateFailureKey identifies a failure counter. The counters are
+// shared by all scrapes and /probe targets like the bloat cache, so they are
+// not reset when a new instance connects.
+type certificateFailureKey struct {
+	server string
+	reason string
+}
+
+var (
+	certificateFailuresMu sync.Mutex
+	certificateFailures   = make(map[certificateFailureKey]float64)
+)
+
+// PGServerCertificateCollector reports the certificate chain each server
+// presented in the TLS handshake of the driver when the instance connected,
+// and counts the handshakes that failed. Verification follows the sslmode of
+// the DSN, so failures are only counted where the driver refuses the server
+// or, with sslmode=prefer, falls back to a connection without TLS.
+type PGServerCertificateCollector struct {
+	log *slog.Logger
+}
+
+func NewPGServerCertificateCollector(config collectorConfig) (Collector, error) {
+	return &PGServerCertificateCollector{log: config.logger}, nil
+}
+
+var (
+	serverCertificateNotAfterDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, serverCertificateSubsystem, "not_after_seconds"),
+		"Expiry of the certificates in the chain presented by the server, in unixtime",
+		[]string{"server", "serial_number"},
+		prometheus.Labels{},
+	)
+	serverCertificateInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, serverCertificateSubsystem, "info"),
+		"Subject, issuer and subject alternative names of the certificates in the chain presented by the server",
+		[]string{"server", "serial_number", "subject", "issuer", "sans"},
+		prometheus.Labels{},
+	)
+	serverCertificateVerificationFailuresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, serverCertificateSubsystem, "verification_failures_total"),
+		"Number of TLS handshakes with the server that failed or whose certificate did not pass verification for the sslmode",
+		[]string{"server", "reason"},
+		prometheus.Labels{},
+	)
+)
+
+func (c *PGServerCertificateCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	for _, handshake := range instance.tlsHandshakes() {
+		for _, cert := range handshake.Certificates {
+			serial := cert.SerialNumber.Text(16)
+			ch <- prometheus.MustNewConstMetric(
+				serverCertificateNotAfterDesc,
+				prometheus.GaugeValue,
+				float64(cert.NotAfter.Unix()),
+				handshake.Server, serial,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				serverCertificateInfoDesc,
+				prometheus.GaugeValue,
+				1,
+				handshake.Server, serial, cert.Subject.String(), cert.Issuer.String(), certificateSANs(cert),
+			)
+		}
+	}
+
+	servers, err := config.TLSServers(instance.dsn)
+	if err != nil {
+		return err
+	}
+	certificateFailuresMu.Lock()
+	defer certificateFailuresMu.Unlock()
+	keys := make([]certificateFailureKey, 0, len(certificateFailures))
+	for key := range certificateFailures {
+		if slices.Contains(servers, key.server) {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b certificateFailureKey) int {
+		return cmp.Or(strings.Compare(a.server, b.server), strings.Compare(a.reason, b.reason))
+	})
+	for _, key := range keys {
+		ch <- prometheus.MustNewConstMetric(
+			serverCertificateVerificationFailuresDesc,
+			prometheus.CounterValue,
+			certificateFailures[key],
+			key.server, key.reason,
+		)
+	}
+	return nil
+}
+
+// observeTLSHandshake keeps the last TLS handshake of the driver with each
+// server for the collector, and counts it if it failed.
+func (i *instance) observeTLSHandshake(handshake config.TLSHandshake) {
+	i.tlsMu.Lock()
+	if i.tlsHandshakesByServer == nil {
+		i.tlsHandshakesByServer = make(map[string]config.TLSHandshake)
+	}
+	i.tlsHandshakesByServer[handshake.Server] = handshake
+	i.tlsMu.Unlock()
+
+	if handshake.Err == nil {
+		return
+	}
+	certificateFailuresMu.Lock()
+	defer certificateFailuresMu.Unlock()
+	certificateFailures[certificateFailureKey{
+		server: handshake.Server,
+		reason: certificateFailureReason(handshake.Err, time.Now()),
+	}]++
+}
+
+// tlsHandshakes returns the observed TLS handshakes, ordered by server.
+func (i *instance) tlsHandshakes() []config.TLSHandshake {
+	i.tlsMu.Lock()
+	defer i.tlsMu.Unlock()
+	handshakes := make([]config.TLSHandshake, 0, len(i.tlsHandshakesByServer))
+	for _, handshake := range i.tlsHandshakesByServer {
+		handshakes = append(handshakes, handshake)
+	}
+	slices.SortFunc(handshakes, func(a, b config.TLSHandshake) int {
+		return strings.Compare(a.Server, b.Server)
+	})
+	return handshakes
+}
+
+// certificateFailureReason returns the reason label for a failed handshake.
+func certificateFailureReason(err error, now time.Time) string {
+	var verifyErr *tls.CertificateVerificationError
+	switch {
+	case errors.Is(err, pq.ErrSSLNotSupported):
+		return certificateReasonSSLNotSupported
+	case !errors.As(err, &verifyErr):
+		return certificateReasonHandshakeFailed
+	}
+
+	var invalidErr x509.CertificateInvalidError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		if now.Before(invalidErr.Cert.NotBefore) {
+			return certificateReasonNotYetValid
+		}
+		return certificateReasonExpired
+	case errors.As(err, &authorityErr):
+		return certificateReasonUnknownAuthority
+	case errors.As(err, &hostnameErr):
+		return certificateReasonHostnameMismatch
+	}
+	return certificateReasonInvalid
+}
+
+func certificateSANs(cert *x509.Certificate) string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return strings.Join(sans, ",")
+}
@@ -0,0 +1,164 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus-community/postgres_exporter/config"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, serial int64, commonName string, dnsNames []string, notBefore, notAfter time.Time, parent *testCertificate) testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCertificate{cert: cert, key: key}
+}
+
+func TestPGServerCertificateCollector(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, 1, "Test CA", nil, now.Add(-time.Hour), now.Add(24*time.Hour), nil)
+	notAfter := time.Unix(now.Add(30*24*time.Hour).Unix(), 0)
+	leaf := newTestCertificate(t, 0xbeef, "db.example.com", []string{"db.example.com", "db"}, now.Add(-time.Hour), notAfter, &ca)
+
+	inst := &instance{dsn: "host=db.example.com port=5432 sslmode=require"}
+	inst.observeTLSHandshake(config.TLSHandshake{
+		Server:       "db.example.com:5432",
+		Certificates: []*x509.Certificate{leaf.cert, ca.cert},
+	})
+
+	testCollectorUpdate(t, &PGServerCertificateCollector{}, inst, nil, []MetricResult{
+		{labels: labelMap{"server": "db.example.com:5432", "serial_number": "beef"}, metricType: dto.MetricType_GAUGE, value: float64(notAfter.Unix())},
+		{labels: labelMap{"server": "db.example.com:5432", "serial_number": "beef", "subject": "CN=db.example.com", "issuer": "CN=Test CA", "sans": "db.example.com,db"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"server": "db.example.com:5432", "serial_number": "1"}, metricType: dto.MetricType_GAUGE, value: float64(ca.cert.NotAfter.Unix())},
+		{labels: labelMap{"server": "db.example.com:5432", "serial_number": "1", "subject": "CN=Test CA", "issuer": "CN=Test CA", "sans": ""}, metricType: dto.MetricType_GAUGE, value: 1},
+	})
+}
+
+func TestPGServerCertificateCollectorFailures(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, 1, "Test CA", nil, now.Add(-time.Hour), now.Add(24*time.Hour), nil)
+	leaf := newTestCertificate(t, 2, "primary.example.com", []string{"primary.example.com"}, now.Add(-time.Hour), now.Add(time.Hour), &ca)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	_, err := leaf.cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "failures.example.com"})
+	mismatch := &tls.CertificateVerificationError{UnverifiedCertificates: []*x509.Certificate{leaf.cert}, Err: err}
+
+	dsn := "host=failures.example.com,standby.example.com sslmode=verify-full"
+	info := labelMap{"server": "failures.example.com:5432", "serial_number": "2", "subject": "CN=primary.example.com", "issuer": "CN=Test CA", "sans": "primary.example.com"}
+
+	// The counters outlive the instance of each scrape, and only count the
+	// servers of the DSN.
+	other := &instance{dsn: "host=other.example.com sslmode=require"}
+	other.observeTLSHandshake(config.TLSHandshake{Server: "other.example.com:5432", Err: pq.ErrSSLNotSupported})
+	for _, failures := range []float64{1, 2} {
+		inst := &instance{dsn: dsn}
+		inst.observeTLSHandshake(config.TLSHandshake{
+			Server:       "failures.example.com:5432",
+			Certificates: []*x509.Certificate{leaf.cert},
+			Err:          mismatch,
+		})
+		testCollectorUpdate(t, &PGServerCertificateCollector{}, inst, nil, []MetricResult{
+			{labels: labelMap{"server": "failures.example.com:5432", "serial_number": "2"}, metricType: dto.MetricType_GAUGE, value: float64(leaf.cert.NotAfter.Unix())},
+			{labels: info, metricType: dto.MetricType_GAUGE, value: 1},
+			{labels: labelMap{"server": "failures.example.com:5432", "reason": "hostname_mismatch"}, metricType: dto.MetricType_COUNTER, value: failures},
+		})
+	}
+
+	// A server that could not be connected to still reports its counters.
+	inst := &instance{dsn: dsn}
+	inst.observeTLSHandshake(config.TLSHandshake{Server: "standby.example.com:5432", Err: tls.AlertError(40)})
+	testCollectorUpdate(t, &PGServerCertificateCollector{}, &instance{dsn: dsn}, nil, []MetricResult{
+		{labels: labelMap{"server": "failures.example.com:5432", "reason": "hostname_mismatch"}, metricType: dto.MetricType_COUNTER, value: 2},
+		{labels: labelMap{"server": "standby.example.com:5432", "reason": "handshake_failed"}, metricType: dto.MetricType_COUNTER, value: 1},
+	})
+}
+
+func TestCertificateFailureReason(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, 1, "Test CA", nil, now.Add(-48*time.Hour), now.Add(48*time.Hour), nil)
+	other := newTestCertificate(t, 2, "Other CA", nil, now.Add(-48*time.Hour), now.Add(48*time.Hour), nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	valid := newTestCertificate(t, 3, "db", []string{"db"}, now.Add(-time.Hour), now.Add(time.Hour), &ca)
+	expired := newTestCertificate(t, 4, "db", []string{"db"}, now.Add(-2*time.Hour), now.Add(-time.Hour), &ca)
+	future := newTestCertificate(t, 5, "db", []string{"db"}, now.Add(time.Hour), now.Add(2*time.Hour), &ca)
+	untrusted := newTestCertificate(t, 6, "db", []string{"db"}, now.Add(-time.Hour), now.Add(time.Hour), &other)
+	verifyErr := func(cert testCertificate, hostname string) error {
+		_, err := cert.cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: hostname, CurrentTime: now})
+		return &tls.CertificateVerificationError{UnverifiedCertificates: []*x509.Certificate{cert.cert}, Err: err}
+	}
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "hostname mismatch", err: verifyErr(valid, "replica"), want: "hostname_mismatch"},
+		{name: "expired", err: verifyErr(expired, ""), want: "expired"},
+		{name: "not yet valid", err: verifyErr(future, ""), want: "not_yet_valid"},
+		{name: "unknown authority", err: verifyErr(untrusted, ""), want: "unknown_authority"},
+		{name: "invalid", err: &tls.CertificateVerificationError{Err: fmt.Errorf("no certificate")}, want: "invalid"},
+		{name: "ssl not supported", err: pq.ErrSSLNotSupported, want: "ssl_not_supported"},
+		{name: "handshake failed", err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, want: "handshake_failed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := certificateFailureReason(tc.err, now); got != tc.want {
+				t.Fatalf("certificateFailureReason() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
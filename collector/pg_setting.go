@@ -15,12 +15,17 @@ package collector
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"math"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/prometheus-community/postgres_exporter/config"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -28,12 +33,34 @@ func init() {
 	registerCollector(settingsSubsystem, NewPGSettingsCollector)
 }
 
+func defaultSettingsConfig() config.SettingsConfig {
+	return config.SettingsConfig{
+		Audit:       config.DefaultSettingsAudit,
+		Info:        slices.Clone(config.DefaultSettingsInfo),
+		Redact:      slices.Clone(config.DefaultSettingsRedact),
+		Hash:        config.DefaultSettingsHash,
+		HashExclude: slices.Clone(config.DefaultSettingsHashExclude),
+	}
+}
+
 type PGSettingsCollector struct {
-	log *slog.Logger
+	log         *slog.Logger
+	audit       bool
+	info        []string
+	redact      []string
+	hash        bool
+	hashExclude []string
 }
 
-func NewPGSettingsCollector(config collectorConfig) (Collector, error) {
-	return &PGSettingsCollector{log: config.logger}, nil
+func NewPGSettingsCollector(collectorCfg collectorConfig) (Collector, error) {
+	return &PGSettingsCollector{
+		log:         collectorCfg.logger,
+		audit:       collectorCfg.settings.Audit,
+		info:        collectorCfg.settings.Info,
+		redact:      collectorCfg.settings.Redact,
+		hash:        collectorCfg.settings.Hash,
+		hashExclude: collectorCfg.settings.HashExclude,
+	}, nil
 }
 
 var (
@@ -51,8 +78,27 @@ var (
 	// - `sync_commit_cancel_wait`, specific to Azure Postgres, see https://github.com/prometheus-community/postgres_exporter/issues/523
 	// - `google_dataplex.max_messages`, specific to Google Cloud SQL, see https://github.com/prometheus-community/postgres_exporter/issues/1240
 	pgSettingsQuery = "SELECT name, setting, COALESCE(unit, ''), COALESCE(short_desc, ''), vartype FROM pg_settings WHERE vartype IN ('bool', 'integer', 'real') AND name NOT IN ('sync_commit_cancel_wait', 'google_dataplex.max_messages');"
+
+	// Settings passed by the client or set in the session belong to the
+	// exporter's connection, not to the server configuration.
+	pgSettingsAllQuery = "SELECT name, COALESCE(setting, ''), vartype, source FROM pg_settings WHERE source NOT IN ('client', 'session');"
+
+	pgSettingsInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, settingsSubsystem, "info"),
+		"Server Parameter: value of string and enum settings",
+		[]string{"name", "value", "source"},
+		prometheus.Labels{},
+	)
+	pgSettingsHashInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, settingsSubsystem, "hash_info"),
+		"Hash of the effective server configuration, differs between instances whose settings differ",
+		[]string{"hash"},
+		prometheus.Labels{},
+	)
 )
 
+const pgSettingRedacted = "<redacted>"
+
 // Update implements Collector and exposes PostgreSQL runtime settings.
 func (c PGSettingsCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
 	db := instance.getDB()
@@ -74,8 +120,75 @@ func (c PGSettingsCollector) Update(ctx context.Context, instance *instance, ch
 		}
 		ch <- metric
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-	return rows.Err()
+	if !c.audit || (len(c.info) == 0 && !c.hash) {
+		return nil
+	}
+	return c.updateInfo(ctx, instance, ch)
+}
+
+// updateInfo reports the string and enum settings as info metrics and the
+// hash of all settings.
+func (c PGSettingsCollector) updateInfo(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, pgSettingsAllQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var hashed []string
+	for rows.Next() {
+		var name, setting, vartype, source string
+		if err := rows.Scan(&name, &setting, &vartype, &source); err != nil {
+			return err
+		}
+
+		if (vartype == "string" || vartype == "enum") && matchesSettingPattern(c.info, name) {
+			value := setting
+			if matchesSettingPattern(c.redact, name) {
+				value = pgSettingRedacted
+			}
+			ch <- prometheus.MustNewConstMetric(
+				pgSettingsInfoDesc,
+				prometheus.GaugeValue,
+				1,
+				name, value, source,
+			)
+		}
+
+		if c.hash && !matchesSettingPattern(c.hashExclude, name) {
+			hashed = append(hashed, name+"="+setting)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if c.hash {
+		// The collation of the server must not change the hash.
+		slices.Sort(hashed)
+		sum := sha256.Sum256([]byte(strings.Join(hashed, "\n")))
+		ch <- prometheus.MustNewConstMetric(
+			pgSettingsHashInfoDesc,
+			prometheus.GaugeValue,
+			1,
+			hex.EncodeToString(sum[:8]),
+		)
+	}
+	return nil
+}
+
+func matchesSettingPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
 }
 
 // pgSetting represents a PostgreSQL runtime variable as returned by the
@@ -15,6 +15,8 @@ package collector
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -198,3 +200,30 @@ func TestPGSettingsCollectorUpdate(t *testing.T) {
 		t.Errorf("there were unfulfilled exceptions: %s", err)
 	}
 }
+
+func TestPGSettingsCollectorInfo(t *testing.T) {
+	collector := PGSettingsCollector{
+		log:         promslog.NewNopLogger(),
+		audit:       true,
+		info:        []string{"wal_level", "primary_*"},
+		redact:      []string{"*conninfo*"},
+		hash:        true,
+		hashExclude: []string{"primary_conninfo"},
+	}
+
+	sum := sha256.Sum256([]byte("archive_command=\nshared_buffers=16384\nwal_level=logical"))
+	testCollectorUpdate(t, collector, &instance{}, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(pgSettingsQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "setting", "unit", "short_desc", "vartype"}))
+		rows := sqlmock.NewRows([]string{"name", "setting", "vartype", "source"}).
+			AddRow("wal_level", "logical", "enum", "configuration file").
+			AddRow("primary_conninfo", "host=primary password=hunter2", "string", "configuration file").
+			AddRow("shared_buffers", "16384", "integer", "configuration file").
+			AddRow("archive_command", "", "string", "default")
+		mock.ExpectQuery(sanitizeQuery(pgSettingsAllQuery)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{"name": "wal_level", "value": "logical", "source": "configuration file"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"name": "primary_conninfo", "value": "<redacted>", "source": "configuration file"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"hash": hex.EncodeToString(sum[:8])}, metricType: dto.MetricType_GAUGE, value: 1},
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because this set of metrics is only available from Postgres 16
+	registerCollector(statIOSubsystem, NewPGStatIOCollector)
+}
+
+type PGStatIOCollector struct {
+	log *slog.Logger
+}
+
+func NewPGStatIOCollector(config collectorConfig) (Collector, error) {
+	return &PGStatIOCollector{log: config.logger}, nil
+}
+
+func newStatIODesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statIOSubsystem, name),
+		help,
+		[]string{"backend_type", "object", "context"},
+		prometheus.Labels{},
+	)
+}
+
+var (
+	statIOReadsDesc         = newStatIODesc("reads_total", "Number of read operations")
+	statIOReadBytesDesc     = newStatIODesc("read_bytes_total", "Number of bytes read")
+	statIOReadTimeDesc      = newStatIODesc("read_time_seconds_total", "Time spent in read operations, in seconds")
+	statIOWritesDesc        = newStatIODesc("writes_total", "Number of write operations")
+	statIOWriteBytesDesc    = newStatIODesc("write_bytes_total", "Number of bytes written")
+	statIOWriteTimeDesc     = newStatIODesc("write_time_seconds_total", "Time spent in write operations, in seconds")
+	statIOWritebacksDesc    = newStatIODesc("writebacks_total", "Number of units of block size which the process requested the kernel write out to permanent storage")
+	statIOWritebackTimeDesc = newStatIODesc("writeback_time_seconds_total", "Time spent in writeback operations, in seconds")
+	statIOExtendsDesc       = newStatIODesc("extends_total", "Number of relation extend operations")
+	statIOExtendBytesDesc   = newStatIODesc("extend_bytes_total", "Number of bytes added by relation extend operations")
+	statIOExtendTimeDesc    = newStatIODesc("extend_time_seconds_total", "Time spent in extend operations, in seconds")
+	statIOHitsDesc          = newStatIODesc("hits_total", "Number of times a desired block was found in a shared buffer")
+	statIOEvictionsDesc     = newStatIODesc("evictions_total", "Number of times a block has been written out from a shared or local buffer in order to make it available for another use")
+	statIOReusesDesc        = newStatIODesc("reuses_total", "Number of times an existing buffer in a size-limited ring buffer outside of shared buffers was reused")
+	statIOFsyncsDesc        = newStatIODesc("fsyncs_total", "Number of fsync calls")
+	statIOFsyncTimeDesc     = newStatIODesc("fsync_time_seconds_total", "Time spent in fsync operations, in seconds")
+	statIOStatsResetDesc    = newStatIODesc("stats_reset", "Time at which these statistics were last reset")
+
+	// Before PostgreSQL 18 every operation of a row has the same size, op_bytes.
+	statIOQueryBefore18 = `SELECT
+		backend_type
+		,object
+		,context
+		,reads
+		,reads * op_bytes AS read_bytes
+		,read_time
+		,writes
+		,writes * op_bytes AS write_bytes
+		,write_time
+		,writebacks
+		,writeback_time
+		,extends
+		,extends * op_bytes AS extend_bytes
+		,extend_time
+		,hits
+		,evictions
+		,reuses
+		,fsyncs
+		,fsync_time
+		,stats_reset
+	FROM pg_stat_io;`
+
+	// PostgreSQL 18 replaced op_bytes with per-operation byte counters, as WAL
+	// I/O is tracked too and its operations vary in size.
+	statIOQuery18 = `SELECT
+		backend_type
+		,object
+		,context
+		,reads
+		,read_bytes
+		,read_time
+		,writes
+		,write_bytes
+		,write_time
+		,writebacks
+		,writeback_time
+		,extends
+		,extend_bytes
+		,extend_time
+		,hits
+		,evictions
+		,reuses
+		,fsyncs
+		,fsync_time
+		,stats_reset
+	FROM pg_stat_io;`
+)
+
+func (c PGStatIOCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("16.0.0")) {
+		c.log.Warn("pg_stat_io collector is not available on PostgreSQL < 16.0.0, skipping")
+		return nil
+	}
+
+	query := statIOQueryBefore18
+	if instance.version.GTE(semver.MustParse("18.0.0")) {
+		query = statIOQuery18
+	}
+
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var backendType, object, ioContext sql.NullString
+		var reads, readBytes, readTime sql.NullFloat64
+		var writes, writeBytes, writeTime sql.NullFloat64
+		var writebacks, writebackTime sql.NullFloat64
+		var extends, extendBytes, extendTime sql.NullFloat64
+		var hits, evictions, reuses, fsyncs, fsyncTime sql.NullFloat64
+		var statsReset sql.NullTime
+
+		if err := rows.Scan(
+			&backendType, &object, &ioContext,
+			&reads, &readBytes, &readTime,
+			&writes, &writeBytes, &writeTime,
+			&writebacks, &writebackTime,
+			&extends, &extendBytes, &extendTime,
+			&hits, &evictions, &reuses, &fsyncs, &fsyncTime,
+			&statsReset,
+		); err != nil {
+			return err
+		}
+		labels := []string{backendType.String, object.String, ioContext.String}
+
+		// Operations that can not happen for a combination of backend
+		// type, object and context are NULL, they are left out rather
+		// than reported as zero.
+		counters := []struct {
+			desc    *prometheus.Desc
+			value   sql.NullFloat64
+			divisor float64
+		}{
+			{statIOReadsDesc, reads, 1},
+			{statIOReadBytesDesc, readBytes, 1},
+			{statIOReadTimeDesc, readTime, 1000},
+			{statIOWritesDesc, writes, 1},
+			{statIOWriteBytesDesc, writeBytes, 1},
+			{statIOWriteTimeDesc, writeTime, 1000},
+			{statIOWritebacksDesc, writebacks, 1},
+			{statIOWritebackTimeDesc, writebackTime, 1000},
+			{statIOExtendsDesc, extends, 1},
+			{statIOExtendBytesDesc, extendBytes, 1},
+			{statIOExtendTimeDesc, extendTime, 1000},
+			{statIOHitsDesc, hits, 1},
+			{statIOEvictionsDesc, evictions, 1},
+			{statIOReusesDesc, reuses, 1},
+			{statIOFsyncsDesc, fsyncs, 1},
+			{statIOFsyncTimeDesc, fsyncTime, 1000},
+		}
+		for _, counter := range counters {
+			if !counter.value.Valid {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				counter.desc,
+				prometheus.CounterValue,
+				counter.value.Float64/counter.divisor,
+				labels...,
+			)
+		}
+
+		if statsReset.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statIOStatsResetDesc,
+				prometheus.GaugeValue,
+				float64(statsReset.Time.Unix()),
+				labels...,
+			)
+		}
+	}
+	return rows.Err()
+}
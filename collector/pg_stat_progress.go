@@ -0,0 +1,320 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default, enable the progress views that matter for your workload
+	for _, view := range []*progressView{
+		progressAnalyzeView,
+		progressCreateIndexView,
+		progressClusterView,
+		progressCopyView,
+		progressBasebackupView,
+	} {
+		registerCollector(view.subsystem, func(config collectorConfig) (Collector, error) {
+			return &PGStatProgressCollector{log: config.logger, view: view}, nil
+		})
+	}
+}
+
+// progressCounter is an amount of work reported by a progress view. total is
+// empty for work whose total is not known in advance.
+type progressCounter struct {
+	name  string
+	help  string
+	total string
+	done  string
+
+	totalDesc *prometheus.Desc
+	doneDesc  *prometheus.Desc
+}
+
+// progressView describes one of the pg_stat_progress_* views. The query
+// selects the label columns, then the phase, then the total and done column
+// of every counter.
+type progressView struct {
+	subsystem  string
+	view       string
+	minVersion semver.Version
+	labels     []string
+	phases     []string
+	counters   []*progressCounter
+
+	query       string
+	phaseDesc   *prometheus.Desc
+	percentDesc *prometheus.Desc
+}
+
+// progressRelnameColumn resolves relid to a schema-qualified name in the
+// current database and falls back to the OID elsewhere, like the
+// stat_progress_vacuum collector.
+const progressRelnameColumn = `COALESCE(CASE WHEN datname = current_database() THEN NULLIF(relid, 0)::regclass::text END, NULLIF(relid, 0)::text) AS relname`
+
+func newProgressView(v *progressView) *progressView {
+	columns := make([]string, 0, len(v.labels)+1+2*len(v.counters))
+	for _, label := range v.labels {
+		switch label {
+		case "pid":
+			columns = append(columns, "pid::text AS pid")
+		case "relname":
+			columns = append(columns, progressRelnameColumn)
+		default:
+			columns = append(columns, label)
+		}
+	}
+	if len(v.phases) > 0 {
+		columns = append(columns, "phase")
+		v.phaseDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, v.subsystem, "phase"),
+			"Current phase of the operation (1 = active, 0 = inactive). Label 'phase' is human-readable.",
+			append(slices.Clone(v.labels), "phase"),
+			nil,
+		)
+	}
+	for _, counter := range v.counters {
+		total := counter.total
+		if total == "" {
+			total = "NULL::bigint"
+		}
+		columns = append(columns, total, counter.done)
+		counter.totalDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, v.subsystem, counter.name),
+			"Total number of "+counter.help+" to process.",
+			v.labels,
+			nil,
+		)
+		counter.doneDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, v.subsystem, counter.name+"_done"),
+			"Number of "+counter.help+" processed so far.",
+			v.labels,
+			nil,
+		)
+	}
+	v.percentDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, v.subsystem, "progress_percent"),
+		"Percentage of the work of the current phase done, based on the first counter with a known total.",
+		v.labels,
+		nil,
+	)
+	v.query = "SELECT " + strings.Join(columns, ", ") + " FROM " + v.view + ";"
+	return v
+}
+
+var (
+	progressAnalyzeView = newProgressView(&progressView{
+		subsystem:  progressAnalyzeSubsystem,
+		view:       "pg_stat_progress_analyze",
+		minVersion: semver.MustParse("13.0.0"),
+		labels:     []string{"pid", "datname", "relname"},
+		phases: []string{
+			"initializing",
+			"acquiring sample rows",
+			"acquiring inherited sample rows",
+			"computing statistics",
+			"computing extended statistics",
+			"finalizing analyze",
+		},
+		counters: []*progressCounter{
+			{name: "blocks", help: "heap blocks to sample", total: "sample_blks_total", done: "sample_blks_scanned"},
+			{name: "extended_statistics", help: "extended statistics", total: "ext_stats_total", done: "ext_stats_computed"},
+			{name: "child_tables", help: "child tables", total: "child_tables_total", done: "child_tables_done"},
+		},
+	})
+
+	progressCreateIndexView = newProgressView(&progressView{
+		subsystem:  progressCreateIndexSubsystem,
+		view:       "pg_stat_progress_create_index",
+		minVersion: semver.MustParse("12.0.0"),
+		labels:     []string{"pid", "datname", "relname", "command"},
+		phases: []string{
+			"initializing",
+			"waiting for writers before build",
+			"building index",
+			"waiting for writers before validation",
+			"index validation: scanning index",
+			"index validation: sorting tuples",
+			"index validation: scanning table",
+			"waiting for old snapshots",
+			"waiting for readers before marking dead",
+			"waiting for readers before dropping",
+		},
+		counters: []*progressCounter{
+			{name: "blocks", help: "blocks", total: "blocks_total", done: "blocks_done"},
+			{name: "tuples", help: "tuples", total: "tuples_total", done: "tuples_done"},
+			{name: "lockers", help: "lockers to wait for", total: "lockers_total", done: "lockers_done"},
+			{name: "partitions", help: "partitions", total: "partitions_total", done: "partitions_done"},
+		},
+	})
+
+	progressClusterView = newProgressView(&progressView{
+		subsystem:  progressClusterSubsystem,
+		view:       "pg_stat_progress_cluster",
+		minVersion: semver.MustParse("12.0.0"),
+		labels:     []string{"pid", "datname", "relname", "command"},
+		phases: []string{
+			"initializing",
+			"seq scanning heap",
+			"index scanning heap",
+			"sorting tuples",
+			"writing new heap",
+			"swapping relation files",
+			"rebuilding index",
+			"performing final cleanup",
+		},
+		counters: []*progressCounter{
+			{name: "blocks", help: "heap blocks", total: "heap_blks_total", done: "heap_blks_scanned"},
+			{name: "tuples", help: "heap tuples written", done: "heap_tuples_written"},
+			{name: "indexes", help: "indexes rebuilt", done: "index_rebuild_count"},
+		},
+	})
+
+	progressCopyView = newProgressView(&progressView{
+		subsystem:  progressCopySubsystem,
+		view:       "pg_stat_progress_copy",
+		minVersion: semver.MustParse("14.0.0"),
+		labels:     []string{"pid", "datname", "relname", "command", "type"},
+		counters: []*progressCounter{
+			{name: "bytes", help: "bytes", total: "bytes_total", done: "bytes_processed"},
+			{name: "tuples", help: "tuples", done: "tuples_processed"},
+		},
+	})
+
+	progressBasebackupView = newProgressView(&progressView{
+		subsystem:  progressBasebackupSubsystem,
+		view:       "pg_stat_progress_basebackup",
+		minVersion: semver.MustParse("13.0.0"),
+		labels:     []string{"pid"},
+		phases: []string{
+			"initializing",
+			"waiting for checkpoint to finish",
+			"estimating backup size",
+			"streaming database files",
+			"waiting for wal archiving to finish",
+			"transferring wal files",
+		},
+		counters: []*progressCounter{
+			{name: "bytes", help: "bytes", total: "backup_total", done: "backup_streamed"},
+			{name: "tablespaces", help: "tablespaces", total: "tablespaces_total", done: "tablespaces_streamed"},
+		},
+	})
+)
+
+// PGStatProgressCollector reports the progress of the operations in one of
+// the pg_stat_progress_* views.
+type PGStatProgressCollector struct {
+	log  *slog.Logger
+	view *progressView
+}
+
+func (c *PGStatProgressCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	v := c.view
+	if instance.version.LT(v.minVersion) {
+		c.log.Debug("Progress view is not available, skipping", "view", v.view, "min_version", v.minVersion)
+		return nil
+	}
+
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, v.query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		labels := make([]sql.NullString, len(v.labels))
+		var phase sql.NullString
+		values := make([]sql.NullInt64, 2*len(v.counters))
+
+		dest := make([]any, 0, len(labels)+1+len(values))
+		for i := range labels {
+			dest = append(dest, &labels[i])
+		}
+		if len(v.phases) > 0 {
+			dest = append(dest, &phase)
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		labelValues := make([]string, len(labels))
+		for i, label := range labels {
+			labelValues[i] = "unknown"
+			if label.Valid {
+				labelValues[i] = label.String
+			}
+		}
+
+		if len(v.phases) > 0 {
+			current := progressPhase(v.phases, phase)
+			for _, p := range v.phases {
+				value := 0.0
+				// Only the current phase should be 1.0.
+				if p == current {
+					value = 1.0
+				}
+				ch <- prometheus.MustNewConstMetric(v.phaseDesc, prometheus.GaugeValue, value, append(labelValues, p)...)
+			}
+		}
+
+		percentDone := false
+		for i, counter := range v.counters {
+			total, done := values[2*i], values[2*i+1]
+			if total.Valid {
+				ch <- prometheus.MustNewConstMetric(counter.totalDesc, prometheus.GaugeValue, float64(total.Int64), labelValues...)
+			}
+			if done.Valid {
+				ch <- prometheus.MustNewConstMetric(counter.doneDesc, prometheus.GaugeValue, float64(done.Int64), labelValues...)
+			}
+			if !percentDone && total.Valid && total.Int64 > 0 && done.Valid {
+				percentDone = true
+				ch <- prometheus.MustNewConstMetric(
+					v.percentDesc,
+					prometheus.GaugeValue,
+					100*float64(done.Int64)/float64(total.Int64),
+					labelValues...,
+				)
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// progressPhase returns the phase of phases that the reported phase is in.
+// Index access methods add their own sub-phase, as in "building index:
+// scanning table", which is reported as the phase before the colon.
+func progressPhase(phases []string, phase sql.NullString) string {
+	if !phase.Valid {
+		return ""
+	}
+	if slices.Contains(phases, phase.String) {
+		return phase.String
+	}
+	prefix, _, _ := strings.Cut(phase.String, ":")
+	return prefix
+}
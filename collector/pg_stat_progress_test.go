@@ -0,0 +1,138 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestPGStatProgressCreateIndexCollector(t *testing.T) {
+	columns := []string{
+		"pid", "datname", "relname", "command", "phase",
+		"blocks_total", "blocks_done", "tuples_total", "tuples_done",
+		"lockers_total", "lockers_done", "partitions_total", "partitions_done",
+	}
+	// The btree access method reports its own sub-phase.
+	rows := sqlmock.NewRows(columns).AddRow(
+		"4242", "app", "public.orders", "REINDEX CONCURRENTLY", "building index: scanning table",
+		8000, 2000, 0, 0, 0, 0, 0, 0)
+
+	labels := labelMap{"pid": "4242", "datname": "app", "relname": "public.orders", "command": "REINDEX CONCURRENTLY"}
+	phase := func(name string, value float64) MetricResult {
+		l := labelMap{"phase": name}
+		for k, v := range labels {
+			l[k] = v
+		}
+		return MetricResult{labels: l, metricType: dto.MetricType_GAUGE, value: value}
+	}
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGStatProgressCollector{view: progressCreateIndexView}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(progressCreateIndexView.query)).WillReturnRows(rows)
+	}, []MetricResult{
+		phase("initializing", 0),
+		phase("waiting for writers before build", 0),
+		phase("building index", 1),
+		phase("waiting for writers before validation", 0),
+		phase("index validation: scanning index", 0),
+		phase("index validation: sorting tuples", 0),
+		phase("index validation: scanning table", 0),
+		phase("waiting for old snapshots", 0),
+		phase("waiting for readers before marking dead", 0),
+		phase("waiting for readers before dropping", 0),
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 8000},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 2000},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 25},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 0},
+	})
+}
+
+func TestPGStatProgressCopyCollector(t *testing.T) {
+	columns := []string{
+		"pid", "datname", "relname", "command", "type",
+		"bytes_total", "bytes_processed", "tuples_total", "tuples_processed",
+	}
+	// COPY FROM STDIN does not know the total size, and COPY (query) TO has
+	// no relation.
+	rows := sqlmock.NewRows(columns).
+		AddRow("100", "app", "public.events", "COPY FROM", "PIPE", 0, 65536, nil, 1000).
+		AddRow("101", "app", nil, "COPY TO", "FILE", 1048576, 262144, nil, 50)
+
+	events := labelMap{"pid": "100", "datname": "app", "relname": "public.events", "command": "COPY FROM", "type": "PIPE"}
+	query := labelMap{"pid": "101", "datname": "app", "relname": "unknown", "command": "COPY TO", "type": "FILE"}
+	inst := &instance{version: semver.MustParse("14.0.0")}
+	testCollectorUpdate(t, &PGStatProgressCollector{view: progressCopyView}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(progressCopyView.query)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 65536},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 1000},
+		{labels: query, metricType: dto.MetricType_GAUGE, value: 1048576},
+		{labels: query, metricType: dto.MetricType_GAUGE, value: 262144},
+		{labels: query, metricType: dto.MetricType_GAUGE, value: 25},
+		{labels: query, metricType: dto.MetricType_GAUGE, value: 50},
+	})
+}
+
+func TestPGStatProgressBasebackupCollector(t *testing.T) {
+	columns := []string{
+		"pid", "phase",
+		"backup_total", "backup_streamed", "tablespaces_total", "tablespaces_streamed",
+	}
+	// Without progress estimation backup_total is NULL, the tablespaces
+	// give the percentage.
+	rows := sqlmock.NewRows(columns).AddRow("77", "streaming database files", nil, 5368709120, 4, 1)
+
+	labels := labelMap{"pid": "77"}
+	phase := func(name string, value float64) MetricResult {
+		return MetricResult{labels: labelMap{"pid": "77", "phase": name}, metricType: dto.MetricType_GAUGE, value: value}
+	}
+	inst := &instance{version: semver.MustParse("15.0.0")}
+	testCollectorUpdate(t, &PGStatProgressCollector{view: progressBasebackupView}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(progressBasebackupView.query)).WillReturnRows(rows)
+	}, []MetricResult{
+		phase("initializing", 0),
+		phase("waiting for checkpoint to finish", 0),
+		phase("estimating backup size", 0),
+		phase("streaming database files", 1),
+		phase("waiting for wal archiving to finish", 0),
+		phase("transferring wal files", 0),
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 5368709120},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 4},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labels, metricType: dto.MetricType_GAUGE, value: 25},
+	})
+}
+
+func TestPGStatProgressCollectorUnavailable(t *testing.T) {
+	inst := &instance{version: semver.MustParse("13.0.0")}
+	testCollectorUpdate(t, &PGStatProgressCollector{log: promslog.NewNopLogger(), view: progressCopyView}, inst, nil, nil)
+}
+
+func TestProgressViewQuery(t *testing.T) {
+	want := "SELECT pid::text AS pid, datname, " + progressRelnameColumn + ", command, phase, " +
+		"heap_blks_total, heap_blks_scanned, NULL::bigint, heap_tuples_written, NULL::bigint, index_rebuild_count " +
+		"FROM pg_stat_progress_cluster;"
+	if progressClusterView.query != want {
+		t.Fatalf("query = %q, want %q", progressClusterView.query, want)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because this set of metrics is only available from Postgres 14
+	registerCollector(statReplicationSlotsSubsystem, NewPGStatReplicationSlotsCollector)
+}
+
+type PGStatReplicationSlotsCollector struct {
+	log *slog.Logger
+}
+
+func NewPGStatReplicationSlotsCollector(config collectorConfig) (Collector, error) {
+	return &PGStatReplicationSlotsCollector{log: config.logger}, nil
+}
+
+func newStatReplicationSlotsDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statReplicationSlotsSubsystem, name),
+		help,
+		[]string{"slot_name"},
+		prometheus.Labels{},
+	)
+}
+
+var (
+	statReplicationSlotsSpillTxnsDesc   = newStatReplicationSlotsDesc("spill_txns_total", "Number of transactions spilled to disk once the memory used by logical decoding exceeded logical_decoding_work_mem")
+	statReplicationSlotsSpillCountDesc  = newStatReplicationSlotsDesc("spill_count_total", "Number of times transactions were spilled to disk while decoding changes from WAL for this slot")
+	statReplicationSlotsSpillBytesDesc  = newStatReplicationSlotsDesc("spill_bytes_total", "Amount of decoded transaction data spilled to disk")
+	statReplicationSlotsStreamTxnsDesc  = newStatReplicationSlotsDesc("stream_txns_total", "Number of in-progress transactions streamed to the decoding output plugin")
+	statReplicationSlotsStreamCountDesc = newStatReplicationSlotsDesc("stream_count_total", "Number of times in-progress transactions were streamed to the decoding output plugin")
+	statReplicationSlotsStreamBytesDesc = newStatReplicationSlotsDesc("stream_bytes_total", "Amount of transaction data decoded for streaming in-progress transactions")
+	statReplicationSlotsTotalTxnsDesc   = newStatReplicationSlotsDesc("decoded_txns_total", "Number of decoded transactions sent to the decoding output plugin")
+	statReplicationSlotsTotalBytesDesc  = newStatReplicationSlotsDesc("decoded_bytes_total", "Amount of transaction data decoded for sending transactions to the decoding output plugin")
+	statReplicationSlotsStatsResetDesc  = newStatReplicationSlotsDesc("stats_reset", "Time at which these statistics were last reset")
+
+	statReplicationSlotsQuery = `SELECT
+		slot_name
+		,spill_txns
+		,spill_count
+		,spill_bytes
+		,stream_txns
+		,stream_count
+		,stream_bytes
+		,total_txns
+		,total_bytes
+		,stats_reset
+	FROM pg_stat_replication_slots;`
+)
+
+func (c PGStatReplicationSlotsCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("14.0.0")) {
+		c.log.Warn("pg_stat_replication_slots collector is not available on PostgreSQL < 14.0.0, skipping")
+		return nil
+	}
+
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, statReplicationSlotsQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slotName sql.NullString
+		var spillTxns, spillCount, spillBytes sql.NullInt64
+		var streamTxns, streamCount, streamBytes sql.NullInt64
+		var totalTxns, totalBytes sql.NullInt64
+		var statsReset sql.NullTime
+
+		if err := rows.Scan(
+			&slotName,
+			&spillTxns, &spillCount, &spillBytes,
+			&streamTxns, &streamCount, &streamBytes,
+			&totalTxns, &totalBytes,
+			&statsReset,
+		); err != nil {
+			return err
+		}
+		if !slotName.Valid {
+			continue
+		}
+
+		counters := []struct {
+			desc  *prometheus.Desc
+			value sql.NullInt64
+		}{
+			{statReplicationSlotsSpillTxnsDesc, spillTxns},
+			{statReplicationSlotsSpillCountDesc, spillCount},
+			{statReplicationSlotsSpillBytesDesc, spillBytes},
+			{statReplicationSlotsStreamTxnsDesc, streamTxns},
+			{statReplicationSlotsStreamCountDesc, streamCount},
+			{statReplicationSlotsStreamBytesDesc, streamBytes},
+			{statReplicationSlotsTotalTxnsDesc, totalTxns},
+			{statReplicationSlotsTotalBytesDesc, totalBytes},
+		}
+		for _, counter := range counters {
+			value := 0.0
+			if counter.value.Valid {
+				value = float64(counter.value.Int64)
+			}
+			ch <- prometheus.MustNewConstMetric(
+				counter.desc,
+				prometheus.CounterValue,
+				value,
+				slotName.String,
+			)
+		}
+
+		statsResetMetric := 0.0
+		if statsReset.Valid {
+			statsResetMetric = float64(statsReset.Time.Unix())
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statReplicationSlotsStatsResetDesc,
+			prometheus.GaugeValue,
+			statsResetMetric,
+			slotName.String,
+		)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,124 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestPGStatReplicationSlotsCollector(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening a stub db connection: %s", err)
+	}
+	defer db.Close()
+
+	inst := &instance{db: db, version: semver.MustParse("14.0.0")}
+
+	columns := []string{
+		"slot_name",
+		"spill_txns",
+		"spill_count",
+		"spill_bytes",
+		"stream_txns",
+		"stream_count",
+		"stream_bytes",
+		"total_txns",
+		"total_bytes",
+		"stats_reset"}
+
+	srT, err := time.Parse("2006-01-02 15:04:05.00000-07", "2023-05-25 17:10:42.81132-07")
+	if err != nil {
+		t.Fatalf("Error parsing time: %s", err)
+	}
+
+	rows := sqlmock.NewRows(columns).
+		AddRow("debezium", 12, 40, 734003200, 3, 9, 1048576, 15000, 2147483648, srT).
+		AddRow("fresh", nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery(sanitizeQuery(statReplicationSlotsQuery)).WillReturnRows(rows)
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		c := PGStatReplicationSlotsCollector{}
+
+		if err := c.Update(context.Background(), inst, ch); err != nil {
+			t.Errorf("Error calling PGStatReplicationSlotsCollector.Update: %s", err)
+		}
+	}()
+
+	debezium := labelMap{"slot_name": "debezium"}
+	fresh := labelMap{"slot_name": "fresh"}
+	expected := []MetricResult{
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 12},
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 40},
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 734003200},
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 3},
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 9},
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 1048576},
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 15000},
+		{labels: debezium, metricType: dto.MetricType_COUNTER, value: 2147483648},
+		{labels: debezium, metricType: dto.MetricType_GAUGE, value: 1685059842},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: fresh, metricType: dto.MetricType_GAUGE, value: 0},
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range expected {
+			m := readMetric(<-ch)
+			convey.So(expect, convey.ShouldResemble, m)
+		}
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled exceptions: %s", err)
+	}
+}
+
+func TestPGStatReplicationSlotsCollectorBefore14(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening a stub db connection: %s", err)
+	}
+	defer db.Close()
+
+	inst := &instance{db: db, version: semver.MustParse("13.0.0")}
+
+	ch := make(chan prometheus.Metric, 1)
+	c := PGStatReplicationSlotsCollector{log: promslog.NewNopLogger()}
+	if err := c.Update(context.Background(), inst, ch); err != nil {
+		t.Fatalf("Error calling PGStatReplicationSlotsCollector.Update: %s", err)
+	}
+	close(ch)
+	if m, ok := <-ch; ok {
+		t.Fatalf("unexpected metric %s", m.Desc())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled exceptions: %s", err)
+	}
+}
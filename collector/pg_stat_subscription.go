@@ -0,0 +1,280 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(statSubscriptionSubsystem, NewPGStatSubscriptionCollector)
+}
+
+type PGStatSubscriptionCollector struct {
+	log *slog.Logger
+}
+
+func NewPGStatSubscriptionCollector(config collectorConfig) (Collector, error) {
+	return &PGStatSubscriptionCollector{log: config.logger}, nil
+}
+
+var (
+	statSubscriptionLabels = []string{"datname", "subname"}
+
+	statSubscriptionActiveDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "active"),
+		"Whether the apply worker of the subscription is running",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionTableSyncWorkersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "table_sync_workers"),
+		"Number of running table synchronization workers of the subscription",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionReceivedLSNDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "received_lsn"),
+		"Last write-ahead log location received by the apply worker represented as a decimal",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionLatestEndLSNDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "latest_end_lsn"),
+		"Last write-ahead log location reported to the origin WAL sender represented as a decimal",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionLatestEndLagDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "latest_end_lag_bytes"),
+		"Bytes of write-ahead log received but not yet reported to the origin WAL sender",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionLastMsgSendAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "last_msg_send_age_seconds"),
+		"Time since the last message received from the origin WAL sender was sent",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionLastMsgReceiptAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "last_msg_receipt_age_seconds"),
+		"Time since the last message from the origin WAL sender was received",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionApplyErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "apply_errors_total"),
+		"Number of times an error occurred while applying changes",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+	statSubscriptionSyncErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statSubscriptionSubsystem, "sync_errors_total"),
+		"Number of times an error occurred during the initial table synchronization",
+		statSubscriptionLabels,
+		prometheus.Labels{},
+	)
+
+	// pg_stat_subscription has a row with a NULL pid for subscriptions
+	// without running workers. Table synchronization workers have a relid.
+	// It covers the whole cluster, while subscription names are only unique
+	// within a database.
+	statSubscriptionQueryTemplate = `SELECT
+		s.subid
+		,d.datname
+		,s.subname
+		,s.pid
+		,s.relid IS NOT NULL AS table_sync
+		,s.received_lsn - '0/0' AS received_lsn
+		,s.latest_end_lsn - '0/0' AS latest_end_lsn
+		,EXTRACT(EPOCH FROM now() - s.last_msg_send_time) AS last_msg_send_age
+		,EXTRACT(EPOCH FROM now() - s.last_msg_receipt_time) AS last_msg_receipt_age
+		,%s
+	FROM pg_stat_subscription s
+	JOIN pg_subscription sub ON sub.oid = s.subid
+	JOIN pg_database d ON d.oid = sub.subdbid
+	%s
+	ORDER BY s.subid;`
+)
+
+func statSubscriptionQuery(version semver.Version) string {
+	errorColumns := "NULL::bigint AS apply_error_count ,NULL::bigint AS sync_error_count"
+	clauses := ""
+	if version.GTE(semver.MustParse("15.0.0")) {
+		errorColumns = "ss.apply_error_count ,ss.sync_error_count"
+		clauses = "LEFT JOIN pg_stat_subscription_stats ss ON ss.subid = s.subid"
+	}
+	if version.GTE(semver.MustParse("16.0.0")) {
+		// Parallel apply workers report on behalf of their leader.
+		clauses += " WHERE s.leader_pid IS NULL"
+	}
+	return fmt.Sprintf(statSubscriptionQueryTemplate, errorColumns, clauses)
+}
+
+// subscriptionStat holds the apply worker statistics of a subscription.
+type subscriptionStat struct {
+	id               int64
+	datname          string
+	name             string
+	active           bool
+	tableSyncWorkers int
+	receivedLSN      sql.NullFloat64
+	latestEndLSN     sql.NullFloat64
+	sendAge          sql.NullFloat64
+	receiptAge       sql.NullFloat64
+	applyErrors      sql.NullInt64
+	syncErrors       sql.NullInt64
+}
+
+func (c PGStatSubscriptionCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("10.0.0")) {
+		c.log.Warn("pg_stat_subscription collector is not available on PostgreSQL < 10.0.0, skipping")
+		return nil
+	}
+
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, statSubscriptionQuery(instance.version))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var stats []*subscriptionStat
+	for rows.Next() {
+		var subid int64
+		var datname, subname sql.NullString
+		var pid sql.NullInt64
+		var tableSync bool
+		var receivedLSN, latestEndLSN, sendAge, receiptAge sql.NullFloat64
+		var applyErrors, syncErrors sql.NullInt64
+		if err := rows.Scan(&subid, &datname, &subname, &pid, &tableSync, &receivedLSN, &latestEndLSN, &sendAge, &receiptAge, &applyErrors, &syncErrors); err != nil {
+			return err
+		}
+		if !datname.Valid || !subname.Valid {
+			continue
+		}
+
+		// Rows are ordered by subid, so the rows of a subscription are adjacent.
+		if len(stats) == 0 || stats[len(stats)-1].id != subid {
+			stats = append(stats, &subscriptionStat{id: subid, datname: datname.String, name: subname.String})
+		}
+		stat := stats[len(stats)-1]
+		if applyErrors.Valid {
+			stat.applyErrors = applyErrors
+		}
+		if syncErrors.Valid {
+			stat.syncErrors = syncErrors
+		}
+		if !pid.Valid {
+			continue
+		}
+		if tableSync {
+			stat.tableSyncWorkers++
+			continue
+		}
+		stat.active = true
+		stat.receivedLSN = receivedLSN
+		stat.latestEndLSN = latestEndLSN
+		stat.sendAge = sendAge
+		stat.receiptAge = receiptAge
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, stat := range stats {
+		activeMetric := 0.0
+		if stat.active {
+			activeMetric = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statSubscriptionActiveDesc,
+			prometheus.GaugeValue,
+			activeMetric,
+			stat.datname, stat.name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			statSubscriptionTableSyncWorkersDesc,
+			prometheus.GaugeValue,
+			float64(stat.tableSyncWorkers),
+			stat.datname, stat.name,
+		)
+
+		if stat.receivedLSN.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statSubscriptionReceivedLSNDesc,
+				prometheus.GaugeValue,
+				stat.receivedLSN.Float64,
+				stat.datname, stat.name,
+			)
+		}
+		if stat.latestEndLSN.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statSubscriptionLatestEndLSNDesc,
+				prometheus.GaugeValue,
+				stat.latestEndLSN.Float64,
+				stat.datname, stat.name,
+			)
+		}
+		if stat.receivedLSN.Valid && stat.latestEndLSN.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statSubscriptionLatestEndLagDesc,
+				prometheus.GaugeValue,
+				max(stat.receivedLSN.Float64-stat.latestEndLSN.Float64, 0),
+				stat.datname, stat.name,
+			)
+		}
+		if stat.sendAge.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statSubscriptionLastMsgSendAgeDesc,
+				prometheus.GaugeValue,
+				stat.sendAge.Float64,
+				stat.datname, stat.name,
+			)
+		}
+		if stat.receiptAge.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statSubscriptionLastMsgReceiptAgeDesc,
+				prometheus.GaugeValue,
+				stat.receiptAge.Float64,
+				stat.datname, stat.name,
+			)
+		}
+
+		if stat.applyErrors.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statSubscriptionApplyErrorsDesc,
+				prometheus.CounterValue,
+				float64(stat.applyErrors.Int64),
+				stat.datname, stat.name,
+			)
+		}
+		if stat.syncErrors.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				statSubscriptionSyncErrorsDesc,
+				prometheus.CounterValue,
+				float64(stat.syncErrors.Int64),
+				stat.datname, stat.name,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var statSubscriptionColumns = []string{
+	"subid",
+	"datname",
+	"subname",
+	"pid",
+	"table_sync",
+	"received_lsn",
+	"latest_end_lsn",
+	"last_msg_send_age",
+	"last_msg_receipt_age",
+	"apply_error_count",
+	"sync_error_count",
+}
+
+func TestPGStatSubscriptionCollector(t *testing.T) {
+	inst := &instance{version: semver.MustParse("16.0.0")}
+
+	// orders in shop has its apply worker and a table synchronization worker
+	// running, the apply worker of the subscription of the same name in
+	// archive is not running.
+	rows := sqlmock.NewRows(statSubscriptionColumns).
+		AddRow(16401, "shop", "orders", 4123, false, 5000, 4200, 1.5, 1.25, 2, 1).
+		AddRow(16401, "shop", "orders", 4124, true, 4000, 4000, 0.5, 0.5, 2, 1).
+		AddRow(16502, "archive", "orders", nil, false, nil, nil, nil, nil, 7, 0)
+
+	shop := labelMap{"datname": "shop", "subname": "orders"}
+	archive := labelMap{"datname": "archive", "subname": "orders"}
+	testCollectorUpdate(t, PGStatSubscriptionCollector{}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(statSubscriptionQuery(inst.version))).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: shop, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: shop, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: shop, metricType: dto.MetricType_GAUGE, value: 5000},
+		{labels: shop, metricType: dto.MetricType_GAUGE, value: 4200},
+		{labels: shop, metricType: dto.MetricType_GAUGE, value: 800},
+		{labels: shop, metricType: dto.MetricType_GAUGE, value: 1.5},
+		{labels: shop, metricType: dto.MetricType_GAUGE, value: 1.25},
+		{labels: shop, metricType: dto.MetricType_COUNTER, value: 2},
+		{labels: shop, metricType: dto.MetricType_COUNTER, value: 1},
+		{labels: archive, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: archive, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: archive, metricType: dto.MetricType_COUNTER, value: 7},
+		{labels: archive, metricType: dto.MetricType_COUNTER, value: 0},
+	})
+}
+
+func TestPGStatSubscriptionCollectorBefore15(t *testing.T) {
+	inst := &instance{version: semver.MustParse("14.0.0")}
+
+	rows := sqlmock.NewRows(statSubscriptionColumns).
+		AddRow(16401, "shop", "orders", nil, false, nil, nil, nil, nil, nil, nil)
+
+	orders := labelMap{"datname": "shop", "subname": "orders"}
+	testCollectorUpdate(t, PGStatSubscriptionCollector{}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(sanitizeQuery(statSubscriptionQuery(inst.version))).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: orders, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: orders, metricType: dto.MetricType_GAUGE, value: 0},
+	})
+}
+
+func TestStatSubscriptionQuery(t *testing.T) {
+	tests := []struct {
+		version     string
+		contains    []string
+		notContains []string
+	}{
+		{version: "14.0.0", notContains: []string{"pg_stat_subscription_stats", "leader_pid"}},
+		{version: "15.0.0", contains: []string{"pg_stat_subscription_stats"}, notContains: []string{"leader_pid"}},
+		{version: "17.0.0", contains: []string{"pg_stat_subscription_stats", "WHERE s.leader_pid IS NULL"}},
+	}
+	for _, test := range tests {
+		query := statSubscriptionQuery(semver.MustParse(test.version))
+		for _, s := range test.contains {
+			if !strings.Contains(query, s) {
+				t.Errorf("query for %s does not contain %q", test.version, s)
+			}
+		}
+		for _, s := range test.notContains {
+			if strings.Contains(query, s) {
+				t.Errorf("query for %s contains %q", test.version, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/prometheus-community/postgres_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because functions are only tracked with track_functions enabled
+	//   and every function causes new timeseries to be created
+	registerCollector(statUserFunctionsSubsystem, NewPGStatUserFunctionsCollector)
+}
+
+func defaultPGStatUserFunctionsConfig() config.PGStatUserFunctionsConfig {
+	return config.PGStatUserFunctionsConfig{
+		Limit: config.DefaultPGStatUserFunctionsLimit,
+	}
+}
+
+func withPGStatUserFunctionsDefaults(c config.PGStatUserFunctionsConfig) config.PGStatUserFunctionsConfig {
+	if c.Limit == 0 {
+		c.Limit = config.DefaultPGStatUserFunctionsLimit
+	}
+	return c
+}
+
+type PGStatUserFunctionsCollector struct {
+	log              *slog.Logger
+	includeSchemas   []string
+	excludeSchemas   []string
+	includeFunctions []string
+	excludeFunctions []string
+	limit            uint
+}
+
+func NewPGStatUserFunctionsCollector(collectorCfg collectorConfig) (Collector, error) {
+	cfg := withPGStatUserFunctionsDefaults(collectorCfg.pgStatUserFunctions)
+
+	return &PGStatUserFunctionsCollector{
+		log:              collectorCfg.logger,
+		includeSchemas:   cfg.IncludeSchemas,
+		excludeSchemas:   cfg.ExcludeSchemas,
+		includeFunctions: cfg.IncludeFunctions,
+		excludeFunctions: cfg.ExcludeFunctions,
+		limit:            cfg.Limit,
+	}, nil
+}
+
+var (
+	// Overloaded functions share a name, the identity arguments tell them apart.
+	statUserFunctionsLabels = []string{"datname", "schemaname", "funcname", "arguments"}
+
+	statUserFunctionsCallsTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statUserFunctionsSubsystem, "calls_total"),
+		"Number of times this function has been called",
+		statUserFunctionsLabels,
+		prometheus.Labels{},
+	)
+	statUserFunctionsSecondsTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statUserFunctionsSubsystem, "seconds_total"),
+		"Total time spent in this function and all other functions called by it, in seconds",
+		statUserFunctionsLabels,
+		prometheus.Labels{},
+	)
+	statUserFunctionsSelfSecondsTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statUserFunctionsSubsystem, "self_seconds_total"),
+		"Total time spent in this function itself, not including other functions called by it, in seconds",
+		statUserFunctionsLabels,
+		prometheus.Labels{},
+	)
+)
+
+const (
+	statUserFunctionsIncludeSchemas   = `AND schemaname IN (%s) `
+	statUserFunctionsExcludeSchemas   = `AND schemaname NOT IN (%s) `
+	statUserFunctionsIncludeFunctions = `AND funcname IN (%s) `
+	statUserFunctionsExcludeFunctions = `AND funcname NOT IN (%s) `
+
+	statUserFunctionsQuery = `SELECT
+		current_database() datname,
+		schemaname,
+		funcname,
+		pg_get_function_identity_arguments(funcid) AS arguments,
+		calls,
+		total_time / 1000.0 AS seconds_total,
+		self_time / 1000.0 AS self_seconds_total
+	FROM pg_stat_user_functions
+	WHERE true
+	%s
+	ORDER BY total_time DESC
+	LIMIT %d;`
+)
+
+func (c PGStatUserFunctionsCollector) query() string {
+	filters := c.buildFilterClause(c.includeSchemas, statUserFunctionsIncludeSchemas) +
+		c.buildFilterClause(c.excludeSchemas, statUserFunctionsExcludeSchemas) +
+		c.buildFilterClause(c.includeFunctions, statUserFunctionsIncludeFunctions) +
+		c.buildFilterClause(c.excludeFunctions, statUserFunctionsExcludeFunctions)
+	limit := c.limit
+	if limit == 0 {
+		limit = config.DefaultPGStatUserFunctionsLimit
+	}
+	return fmt.Sprintf(statUserFunctionsQuery, filters, limit)
+}
+
+func (c PGStatUserFunctionsCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, c.query())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, schemaname, funcname, arguments sql.NullString
+		var calls sql.NullInt64
+		var secondsTotal, selfSecondsTotal sql.NullFloat64
+		if err := rows.Scan(&datname, &schemaname, &funcname, &arguments, &calls, &secondsTotal, &selfSecondsTotal); err != nil {
+			return err
+		}
+		if !schemaname.Valid || !funcname.Valid {
+			c.log.Debug("Skipping function with no name")
+			continue
+		}
+		labels := []string{datname.String, schemaname.String, funcname.String, arguments.String}
+
+		callsMetric := 0.0
+		if calls.Valid {
+			callsMetric = float64(calls.Int64)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statUserFunctionsCallsTotal,
+			prometheus.CounterValue,
+			callsMetric,
+			labels...,
+		)
+
+		secondsTotalMetric := 0.0
+		if secondsTotal.Valid {
+			secondsTotalMetric = secondsTotal.Float64
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statUserFunctionsSecondsTotal,
+			prometheus.CounterValue,
+			secondsTotalMetric,
+			labels...,
+		)
+
+		selfSecondsTotalMetric := 0.0
+		if selfSecondsTotal.Valid {
+			selfSecondsTotalMetric = selfSecondsTotal.Float64
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statUserFunctionsSelfSecondsTotal,
+			prometheus.CounterValue,
+			selfSecondsTotalMetric,
+			labels...,
+		)
+	}
+	return rows.Err()
+}
+
+func (c PGStatUserFunctionsCollector) buildFilterClause(identifiers []string, clauseTemplate string) string {
+	if len(identifiers) == 0 {
+		return ""
+	}
+
+	escaped := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		escaped = append(escaped, fmt.Sprintf("'%s'", strings.ReplaceAll(identifier, "'", "''")))
+	}
+
+	return fmt.Sprintf(clauseTemplate, strings.Join(escaped, ", "))
+}
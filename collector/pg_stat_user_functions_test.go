@@ -0,0 +1,135 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/smartystreets/goconvey/convey"
+)
+
+var statUserFunctionsColumns = []string{
+	"datname",
+	"schemaname",
+	"funcname",
+	"arguments",
+	"calls",
+	"seconds_total",
+	"self_seconds_total",
+}
+
+func TestPGStatUserFunctionsCollector(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening a stub db connection: %s", err)
+	}
+	defer db.Close()
+
+	inst := &instance{db: db}
+
+	rows := sqlmock.NewRows(statUserFunctionsColumns).
+		AddRow("app", "billing", "charge", "account_id bigint, amount numeric", 1200, 42.5, 30.25).
+		AddRow("app", "billing", "charge", "account_id bigint", 300, 2.5, 2.5).
+		AddRow("app", "public", "touch", "", nil, nil, nil)
+	mock.ExpectQuery(sanitizeQuery(fmt.Sprintf(statUserFunctionsQuery, "", 100))).WillReturnRows(rows)
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		c := PGStatUserFunctionsCollector{}
+
+		if err := c.Update(context.Background(), inst, ch); err != nil {
+			t.Errorf("Error calling PGStatUserFunctionsCollector.Update: %s", err)
+		}
+	}()
+
+	charge2 := labelMap{"datname": "app", "schemaname": "billing", "funcname": "charge", "arguments": "account_id bigint, amount numeric"}
+	charge1 := labelMap{"datname": "app", "schemaname": "billing", "funcname": "charge", "arguments": "account_id bigint"}
+	touch := labelMap{"datname": "app", "schemaname": "public", "funcname": "touch", "arguments": ""}
+	expected := []MetricResult{
+		{labels: charge2, metricType: dto.MetricType_COUNTER, value: 1200},
+		{labels: charge2, metricType: dto.MetricType_COUNTER, value: 42.5},
+		{labels: charge2, metricType: dto.MetricType_COUNTER, value: 30.25},
+		{labels: charge1, metricType: dto.MetricType_COUNTER, value: 300},
+		{labels: charge1, metricType: dto.MetricType_COUNTER, value: 2.5},
+		{labels: charge1, metricType: dto.MetricType_COUNTER, value: 2.5},
+		{labels: touch, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: touch, metricType: dto.MetricType_COUNTER, value: 0},
+		{labels: touch, metricType: dto.MetricType_COUNTER, value: 0},
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range expected {
+			m := readMetric(<-ch)
+			convey.So(expect, convey.ShouldResemble, m)
+		}
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled exceptions: %s", err)
+	}
+}
+
+func TestPGStatUserFunctionsCollectorWithFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error opening a stub db connection: %s", err)
+	}
+	defer db.Close()
+
+	inst := &instance{db: db}
+
+	c := PGStatUserFunctionsCollector{
+		includeSchemas:   []string{"billing", "o'brien"},
+		excludeSchemas:   []string{"pg_temp"},
+		includeFunctions: []string{"charge"},
+		excludeFunctions: []string{"refund", "void"},
+		limit:            5,
+	}
+	filters := "AND schemaname IN ('billing', 'o''brien') " +
+		"AND schemaname NOT IN ('pg_temp') " +
+		"AND funcname IN ('charge') " +
+		"AND funcname NOT IN ('refund', 'void') "
+	rows := sqlmock.NewRows(statUserFunctionsColumns).
+		AddRow("app", "billing", "charge", "account_id bigint", 300, 2.5, 2.5)
+	mock.ExpectQuery(sanitizeQuery(fmt.Sprintf(statUserFunctionsQuery, filters, 5))).WillReturnRows(rows)
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		defer close(ch)
+		if err := c.Update(context.Background(), inst, ch); err != nil {
+			t.Errorf("Error calling PGStatUserFunctionsCollector.Update: %s", err)
+		}
+	}()
+
+	labels := labelMap{"datname": "app", "schemaname": "billing", "funcname": "charge", "arguments": "account_id bigint"}
+	expected := []MetricResult{
+		{labels: labels, metricType: dto.MetricType_COUNTER, value: 300},
+		{labels: labels, metricType: dto.MetricType_COUNTER, value: 2.5},
+		{labels: labels, metricType: dto.MetricType_COUNTER, value: 2.5},
+	}
+
+	convey.Convey("Metrics comparison", t, func() {
+		for _, expect := range expected {
+			m := readMetric(<-ch)
+			convey.So(expect, convey.ShouldResemble, m)
+		}
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled exceptions: %s", err)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because this set of metrics is only available from Postgres 14
+	registerCollector(statWALSubsystem, NewPGStatWALCollector)
+}
+
+type PGStatWALCollector struct {
+	log *slog.Logger
+}
+
+func NewPGStatWALCollector(config collectorConfig) (Collector, error) {
+	return &PGStatWALCollector{log: config.logger}, nil
+}
+
+var (
+	statWALRecordsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "records_total"),
+		"Total number of WAL records generated",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALFPIDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "fpi_total"),
+		"Total number of WAL full page images generated",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "bytes_total"),
+		"Total amount of WAL generated in bytes",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALBuffersFullDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "buffers_full_total"),
+		"Number of times WAL data was written to disk because WAL buffers became full",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALWriteDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "write_total"),
+		"Number of times WAL buffers were written out to disk",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALSyncDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "sync_total"),
+		"Number of times WAL files were synced to disk",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALWriteTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "write_time_seconds_total"),
+		"Total amount of time spent writing WAL buffers to disk, in seconds",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALSyncTimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "sync_time_seconds_total"),
+		"Total amount of time spent syncing WAL files to disk, in seconds",
+		[]string{},
+		prometheus.Labels{},
+	)
+	statWALStatsResetDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, statWALSubsystem, "stats_reset"),
+		"Time at which these statistics were last reset",
+		[]string{},
+		prometheus.Labels{},
+	)
+
+	statWALQueryBefore18 = `SELECT
+		wal_records
+		,wal_fpi
+		,wal_bytes
+		,wal_buffers_full
+		,wal_write
+		,wal_sync
+		,wal_write_time
+		,wal_sync_time
+		,stats_reset
+	FROM pg_stat_wal;`
+
+	// PostgreSQL 18 moved the write and sync counters and timings to
+	// pg_stat_io, see the stat_io collector.
+	statWALQuery18 = `SELECT
+		wal_records
+		,wal_fpi
+		,wal_bytes
+		,wal_buffers_full
+		,stats_reset
+	FROM pg_stat_wal;`
+)
+
+func (c PGStatWALCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("14.0.0")) {
+		c.log.Warn("pg_stat_wal collector is not available on PostgreSQL < 14.0.0, skipping")
+		return nil
+	}
+	after18 := instance.version.GTE(semver.MustParse("18.0.0"))
+
+	db := instance.getDB()
+
+	// wal_records      = bigint
+	// wal_fpi          = bigint
+	// wal_bytes        = numeric
+	// wal_buffers_full = bigint
+	// wal_write        = bigint, before 18
+	// wal_sync         = bigint, before 18
+	// wal_write_time   = double precision, in milliseconds, before 18
+	// wal_sync_time    = double precision, in milliseconds, before 18
+	// stats_reset      = timestamp
+
+	var records, fpi, buffersFull, write, sync sql.NullInt64
+	var bytes, writeTime, syncTime sql.NullFloat64
+	var statsReset sql.NullTime
+
+	var err error
+	if after18 {
+		err = db.QueryRowContext(ctx, statWALQuery18).Scan(&records, &fpi, &bytes, &buffersFull, &statsReset)
+	} else {
+		err = db.QueryRowContext(ctx, statWALQueryBefore18).Scan(&records, &fpi, &bytes, &buffersFull, &write, &sync, &writeTime, &syncTime, &statsReset)
+	}
+	if err != nil {
+		return err
+	}
+
+	recordsMetric := 0.0
+	if records.Valid {
+		recordsMetric = float64(records.Int64)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		statWALRecordsDesc,
+		prometheus.CounterValue,
+		recordsMetric,
+	)
+
+	fpiMetric := 0.0
+	if fpi.Valid {
+		fpiMetric = float64(fpi.Int64)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		statWALFPIDesc,
+		prometheus.CounterValue,
+		fpiMetric,
+	)
+
+	bytesMetric := 0.0
+	if bytes.Valid {
+		bytesMetric = bytes.Float64
+	}
+	ch <- prometheus.MustNewConstMetric(
+		statWALBytesDesc,
+		prometheus.CounterValue,
+		bytesMetric,
+	)
+
+	buffersFullMetric := 0.0
+	if buffersFull.Valid {
+		buffersFullMetric = float64(buffersFull.Int64)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		statWALBuffersFullDesc,
+		prometheus.CounterValue,
+		buffersFullMetric,
+	)
+
+	if !after18 {
+		writeMetric := 0.0
+		if write.Valid {
+			writeMetric = float64(write.Int64)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statWALWriteDesc,
+			prometheus.CounterValue,
+			writeMetric,
+		)
+
+		syncMetric := 0.0
+		if sync.Valid {
+			syncMetric = float64(sync.Int64)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statWALSyncDesc,
+			prometheus.CounterValue,
+			syncMetric,
+		)
+
+		writeTimeMetric := 0.0
+		if writeTime.Valid {
+			writeTimeMetric = writeTime.Float64 / 1000.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statWALWriteTimeDesc,
+			prometheus.CounterValue,
+			writeTimeMetric,
+		)
+
+		syncTimeMetric := 0.0
+		if syncTime.Valid {
+			syncTimeMetric = syncTime.Float64 / 1000.0
+		}
+		ch <- prometheus.MustNewConstMetric(
+			statWALSyncTimeDesc,
+			prometheus.CounterValue,
+			syncTimeMetric,
+		)
+	}
+
+	statsResetMetric := 0.0
+	if statsReset.Valid {
+		statsResetMetric = float64(statsReset.Time.Unix())
+	}
+	ch <- prometheus.MustNewConstMetric(
+		statWALStatsResetDesc,
+		prometheus.GaugeValue,
+		statsResetMetric,
+	)
+
+	return nil
+}
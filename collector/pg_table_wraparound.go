@@ -0,0 +1,206 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because every reported relation causes new
+	//   timeseries to be created
+	registerCollector(tableWraparoundSubsystem, NewPGTableWraparoundCollector)
+}
+
+func defaultTableWraparoundConfig() config.TableWraparoundConfig {
+	return config.TableWraparoundConfig{
+		Limit: config.DefaultTableWraparoundLimit,
+	}
+}
+
+func withTableWraparoundDefaults(c config.TableWraparoundConfig) config.TableWraparoundConfig {
+	if c.Limit == 0 {
+		c.Limit = config.DefaultTableWraparoundLimit
+	}
+	return c
+}
+
+type PGTableWraparoundCollector struct {
+	log   *slog.Logger
+	limit uint
+}
+
+func NewPGTableWraparoundCollector(collectorCfg collectorConfig) (Collector, error) {
+	cfg := withTableWraparoundDefaults(collectorCfg.tableWraparound)
+	return &PGTableWraparoundCollector{
+		log:   collectorCfg.logger,
+		limit: cfg.Limit,
+	}, nil
+}
+
+var (
+	tableWraparoundLabels = []string{"datname", "schemaname", "relname", "kind"}
+
+	tableWraparoundXIDAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tableWraparoundSubsystem, "xid_age"),
+		"Age in transaction IDs of relfrozenxid of the relation",
+		tableWraparoundLabels,
+		prometheus.Labels{},
+	)
+	tableWraparoundMXIDAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tableWraparoundSubsystem, "mxid_age"),
+		"Age in multixact IDs of relminmxid of the relation",
+		tableWraparoundLabels,
+		prometheus.Labels{},
+	)
+	tableWraparoundFreezeMaxAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tableWraparoundSubsystem, "freeze_max_age"),
+		"Effective autovacuum_freeze_max_age of the relation, after its storage parameters",
+		tableWraparoundLabels,
+		prometheus.Labels{},
+	)
+	tableWraparoundMultixactFreezeMaxAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tableWraparoundSubsystem, "multixact_freeze_max_age"),
+		"Effective autovacuum_multixact_freeze_max_age of the relation, after its storage parameters",
+		tableWraparoundLabels,
+		prometheus.Labels{},
+	)
+	tableWraparoundForcedVacuumPercent = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tableWraparoundSubsystem, "forced_vacuum_percent"),
+		"Percentage of the way to a forced anti-wraparound vacuum of the relation, the larger of the transaction ID and multixact ID ones",
+		tableWraparoundLabels,
+		prometheus.Labels{},
+	)
+
+	// TOAST tables are reported under the name of the table they belong to.
+	// As in the autovacuum launcher, a TOAST table without storage
+	// parameters of its own uses those of its table, and the parameters can
+	// only lower the freeze max ages. Temporary tables are left out because
+	// autovacuum cannot process them.
+	tableWraparoundQuery = `
+	WITH rels AS (
+		SELECT
+			c.oid AS tableoid,
+			'table' AS kind,
+			c.relfrozenxid,
+			c.relminmxid,
+			c.reloptions
+		FROM pg_catalog.pg_class c
+		WHERE c.relkind IN ('r', 'm')
+			AND c.relpersistence <> 't'
+		UNION ALL
+		SELECT
+			c.oid,
+			'toast',
+			t.relfrozenxid,
+			t.relminmxid,
+			COALESCE(t.reloptions, c.reloptions)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_class t ON t.oid = c.reltoastrelid
+		WHERE c.relkind IN ('r', 'm')
+			AND c.relpersistence <> 't'
+	), ages AS (
+		SELECT
+			r.tableoid,
+			r.kind,
+			age(r.relfrozenxid) AS xid_age,
+			mxid_age(r.relminmxid) AS mxid_age,
+			LEAST(
+				current_setting('autovacuum_freeze_max_age')::bigint,
+				(SELECT o.option_value::bigint FROM pg_options_to_table(r.reloptions) o
+					WHERE o.option_name = 'autovacuum_freeze_max_age')
+			) AS freeze_max_age,
+			LEAST(
+				current_setting('autovacuum_multixact_freeze_max_age')::bigint,
+				(SELECT o.option_value::bigint FROM pg_options_to_table(r.reloptions) o
+					WHERE o.option_name = 'autovacuum_multixact_freeze_max_age')
+			) AS multixact_freeze_max_age
+		FROM rels r
+	)
+	SELECT
+		current_database() AS datname,
+		n.nspname AS schemaname,
+		c.relname,
+		a.kind,
+		a.xid_age,
+		a.mxid_age,
+		a.freeze_max_age,
+		a.multixact_freeze_max_age,
+		100 * GREATEST(
+			a.xid_age::float8 / GREATEST(a.freeze_max_age, 1),
+			a.mxid_age::float8 / GREATEST(a.multixact_freeze_max_age, 1)
+		) AS forced_vacuum_percent
+	FROM ages a
+	JOIN pg_catalog.pg_class c ON c.oid = a.tableoid
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	ORDER BY forced_vacuum_percent DESC, n.nspname, c.relname, a.kind
+	LIMIT %d
+	`
+)
+
+func (c *PGTableWraparoundCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("9.5.0")) {
+		c.log.Warn("table_wraparound collector is not available on PostgreSQL < 9.5.0, skipping")
+		return nil
+	}
+
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(tableWraparoundQuery, c.limit))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, schemaname, relname, kind string
+		var xidAge, mxidAge, freezeMaxAge, multixactFreezeMaxAge, forcedVacuumPercent sql.NullFloat64
+		if err := rows.Scan(
+			&datname, &schemaname, &relname, &kind,
+			&xidAge, &mxidAge, &freezeMaxAge, &multixactFreezeMaxAge, &forcedVacuumPercent,
+		); err != nil {
+			return err
+		}
+
+		labels := []string{datname, schemaname, relname, kind}
+		for _, m := range []struct {
+			desc  *prometheus.Desc
+			value sql.NullFloat64
+		}{
+			{tableWraparoundXIDAge, xidAge},
+			{tableWraparoundMXIDAge, mxidAge},
+			{tableWraparoundFreezeMaxAge, freezeMaxAge},
+			{tableWraparoundMultixactFreezeMaxAge, multixactFreezeMaxAge},
+			{tableWraparoundForcedVacuumPercent, forcedVacuumPercent},
+		} {
+			if !m.value.Valid {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				m.desc,
+				prometheus.GaugeValue,
+				m.value.Float64,
+				labels...,
+			)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,63 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestPGTableWraparoundCollector(t *testing.T) {
+	rows := sqlmock.NewRows([]string{
+		"datname", "schemaname", "relname", "kind",
+		"xid_age", "mxid_age", "freeze_max_age", "multixact_freeze_max_age", "forced_vacuum_percent",
+	}).
+		AddRow("orders", "public", "events", "toast", 150000000, 1200, 200000000, 400000000, 75).
+		AddRow("orders", "public", "ledger", "table", 40000000, 300000000, 200000000, 400000000, 75).
+		AddRow("orders", "public", "events", "table", 50000000, 1200, 100000000, 400000000, 50)
+
+	events := labelMap{"datname": "orders", "schemaname": "public", "relname": "events", "kind": "table"}
+	eventsToast := labelMap{"datname": "orders", "schemaname": "public", "relname": "events", "kind": "toast"}
+	ledger := labelMap{"datname": "orders", "schemaname": "public", "relname": "ledger", "kind": "table"}
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGTableWraparoundCollector{log: promslog.NewNopLogger(), limit: 5}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(tableWraparoundQuery, 5))).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: eventsToast, metricType: dto.MetricType_GAUGE, value: 150000000},
+		{labels: eventsToast, metricType: dto.MetricType_GAUGE, value: 1200},
+		{labels: eventsToast, metricType: dto.MetricType_GAUGE, value: 200000000},
+		{labels: eventsToast, metricType: dto.MetricType_GAUGE, value: 400000000},
+		{labels: eventsToast, metricType: dto.MetricType_GAUGE, value: 75},
+		{labels: ledger, metricType: dto.MetricType_GAUGE, value: 40000000},
+		{labels: ledger, metricType: dto.MetricType_GAUGE, value: 300000000},
+		{labels: ledger, metricType: dto.MetricType_GAUGE, value: 200000000},
+		{labels: ledger, metricType: dto.MetricType_GAUGE, value: 400000000},
+		{labels: ledger, metricType: dto.MetricType_GAUGE, value: 75},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 50000000},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 1200},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 100000000},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 400000000},
+		{labels: events, metricType: dto.MetricType_GAUGE, value: 50},
+	})
+}
+
+func TestPGTableWraparoundCollectorBefore95(t *testing.T) {
+	inst := &instance{version: semver.MustParse("9.4.0")}
+	testCollectorUpdate(t, &PGTableWraparoundCollector{log: promslog.NewNopLogger(), limit: 5}, inst, nil, nil)
+}
@@ -0,0 +1,175 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because pg_tablespace_size() requires CREATE
+	//   privilege on the tablespace or pg_read_all_stats
+	registerCollector(tablespaceSubsystem, NewPGTablespaceCollector)
+}
+
+type PGTablespaceCollector struct {
+	log         *slog.Logger
+	perDatabase bool
+}
+
+func NewPGTablespaceCollector(config collectorConfig) (Collector, error) {
+	return &PGTablespaceCollector{
+		log:         config.logger,
+		perDatabase: config.tablespace.PerDatabase,
+	}, nil
+}
+
+var (
+	tablespaceSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tablespaceSubsystem, "size_bytes"),
+		"Disk space used by the tablespace",
+		[]string{"tablespace"},
+		prometheus.Labels{},
+	)
+	tablespaceInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tablespaceSubsystem, "info"),
+		"Owner, location and options of the tablespace. The location is empty for the built-in tablespaces",
+		[]string{"tablespace", "owner", "location", "options"},
+		prometheus.Labels{},
+	)
+	tablespaceDatabaseSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tablespaceSubsystem, "database_size_bytes"),
+		"Disk space used by the relations of the database in the tablespace",
+		[]string{"datname", "tablespace"},
+		prometheus.Labels{},
+	)
+
+	// pg_tablespace_size() raises an error instead of returning NULL when
+	// the size is not readable, so it is only called for the default
+	// tablespace of the database and the tablespaces the role has CREATE on,
+	// unless the role has the privileges of pg_read_all_stats.
+	tablespaceQuery = `
+	SELECT
+		t.spcname,
+		pg_get_userbyid(t.spcowner) AS owner,
+		pg_tablespace_location(t.oid) AS location,
+		COALESCE(array_to_string(t.spcoptions, ','), '') AS options,
+		CASE
+			WHEN t.oid = d.dattablespace
+				OR has_tablespace_privilege(t.oid, 'CREATE')
+				OR EXISTS (
+					SELECT 1 FROM pg_catalog.pg_roles r
+					WHERE r.rolname = 'pg_read_all_stats' AND pg_has_role(r.oid, 'USAGE')
+				)
+			THEN pg_tablespace_size(t.oid)
+		END AS size_bytes
+	FROM pg_catalog.pg_tablespace t
+	JOIN pg_catalog.pg_database d ON d.datname = current_database()
+	ORDER BY t.spcname
+	`
+
+	// Relations with reltablespace 0 live in the default tablespace of the
+	// database. pg_table_size() of a table includes its TOAST table and the
+	// index on it, which share the tablespace of the table, so neither is
+	// summed on its own. Shared catalogs live in pg_global and belong to no
+	// database.
+	tablespaceDatabaseQuery = `
+	SELECT
+		current_database() AS datname,
+		t.spcname AS tablespace,
+		sum(pg_table_size(c.oid)) AS size_bytes
+	FROM pg_catalog.pg_class c
+	JOIN pg_catalog.pg_database d ON d.datname = current_database()
+	JOIN pg_catalog.pg_tablespace t
+		ON t.oid = CASE WHEN c.reltablespace = 0 THEN d.dattablespace ELSE c.reltablespace END
+	WHERE c.relkind IN ('r', 'i', 'm', 'S')
+		AND c.relnamespace <> 'pg_toast'::regnamespace
+		AND NOT c.relisshared
+	GROUP BY t.spcname
+	ORDER BY t.spcname
+	`
+)
+
+func (c *PGTablespaceCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if err := c.updateTablespaces(ctx, instance, ch); err != nil {
+		return err
+	}
+	if !c.perDatabase {
+		return nil
+	}
+	return c.updateDatabase(ctx, instance, ch)
+}
+
+func (c *PGTablespaceCollector) updateTablespaces(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, tablespaceQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tablespace, owner, location, options string
+		var size sql.NullFloat64
+		if err := rows.Scan(&tablespace, &owner, &location, &options, &size); err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			tablespaceInfoDesc,
+			prometheus.GaugeValue,
+			1,
+			tablespace, owner, location, options,
+		)
+		if size.Valid {
+			ch <- prometheus.MustNewConstMetric(
+				tablespaceSizeDesc,
+				prometheus.GaugeValue,
+				size.Float64,
+				tablespace,
+			)
+		}
+	}
+	return rows.Err()
+}
+
+func (c *PGTablespaceCollector) updateDatabase(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, tablespaceDatabaseQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, tablespace string
+		var size sql.NullFloat64
+		if err := rows.Scan(&datname, &tablespace, &size); err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			tablespaceDatabaseSizeDesc,
+			prometheus.GaugeValue,
+			size.Float64,
+			datname, tablespace,
+		)
+	}
+	return rows.Err()
+}
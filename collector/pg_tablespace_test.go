@@ -0,0 +1,65 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+var tablespaceColumns = []string{"spcname", "owner", "location", "options", "size_bytes"}
+
+func TestPGTablespaceCollector(t *testing.T) {
+	rows := sqlmock.NewRows(tablespaceColumns).
+		AddRow("archive", "postgres", "/mnt/archive/pg", "random_page_cost=8,seq_page_cost=2", 536870912000).
+		AddRow("pg_default", "postgres", "", "", 42991616).
+		AddRow("pg_global", "postgres", "", "", 565248)
+
+	testCollectorUpdate(t, &PGTablespaceCollector{log: promslog.NewNopLogger()}, &instance{}, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(tablespaceQuery)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{"tablespace": "archive", "owner": "postgres", "location": "/mnt/archive/pg", "options": "random_page_cost=8,seq_page_cost=2"}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"tablespace": "archive"}, metricType: dto.MetricType_GAUGE, value: 536870912000},
+		{labels: labelMap{"tablespace": "pg_default", "owner": "postgres", "location": "", "options": ""}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"tablespace": "pg_default"}, metricType: dto.MetricType_GAUGE, value: 42991616},
+		{labels: labelMap{"tablespace": "pg_global", "owner": "postgres", "location": "", "options": ""}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"tablespace": "pg_global"}, metricType: dto.MetricType_GAUGE, value: 565248},
+	})
+}
+
+func TestPGTablespaceCollectorPerDatabase(t *testing.T) {
+	// Without CREATE privilege on archive its size is not read, the info
+	// metric is still reported.
+	tablespaceRows := sqlmock.NewRows(tablespaceColumns).
+		AddRow("archive", "postgres", "/mnt/archive/pg", "", nil).
+		AddRow("pg_default", "postgres", "", "", 42991616)
+	databaseRows := sqlmock.NewRows([]string{"datname", "tablespace", "size_bytes"}).
+		AddRow("orders", "archive", 214748364800).
+		AddRow("orders", "pg_default", 8413184)
+
+	c := &PGTablespaceCollector{log: promslog.NewNopLogger(), perDatabase: true}
+	testCollectorUpdate(t, c, &instance{}, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(tablespaceQuery)).WillReturnRows(tablespaceRows)
+		mock.ExpectQuery(regexp.QuoteMeta(tablespaceDatabaseQuery)).WillReturnRows(databaseRows)
+	}, []MetricResult{
+		{labels: labelMap{"tablespace": "archive", "owner": "postgres", "location": "/mnt/archive/pg", "options": ""}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"tablespace": "pg_default", "owner": "postgres", "location": "", "options": ""}, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: labelMap{"tablespace": "pg_default"}, metricType: dto.MetricType_GAUGE, value: 42991616},
+		{labels: labelMap{"datname": "orders", "tablespace": "archive"}, metricType: dto.MetricType_GAUGE, value: 214748364800},
+		{labels: labelMap{"datname": "orders", "tablespace": "pg_default"}, metricType: dto.MetricType_GAUGE, value: 8413184},
+	})
+}
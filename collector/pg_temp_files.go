@@ -0,0 +1,183 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because pg_ls_tmpdir() requires superuser or
+	//   pg_monitor, and is only available from Postgres 12
+	registerCollector(tempFilesSubsystem, NewPGTempFilesCollector)
+}
+
+type PGTempFilesCollector struct {
+	log *slog.Logger
+}
+
+func NewPGTempFilesCollector(config collectorConfig) (Collector, error) {
+	return &PGTempFilesCollector{log: config.logger}, nil
+}
+
+var (
+	tempFilesCountDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tempFilesSubsystem, "count"),
+		"Number of temporary files currently in the tablespace",
+		[]string{"tablespace"},
+		prometheus.Labels{},
+	)
+	tempFilesSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tempFilesSubsystem, "size_bytes"),
+		"Total size of the temporary files currently in the tablespace",
+		[]string{"tablespace"},
+		prometheus.Labels{},
+	)
+	tempFilesLargestSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tempFilesSubsystem, "largest_size_bytes"),
+		"Size of the largest temporary file currently in the tablespace",
+		[]string{"tablespace"},
+		prometheus.Labels{},
+	)
+	tempFilesBackendCountDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tempFilesSubsystem, "backend_count"),
+		"Number of temporary files currently owned by backends of the user and application",
+		[]string{"usename", "application_name"},
+		prometheus.Labels{},
+	)
+	tempFilesBackendSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, tempFilesSubsystem, "backend_size_bytes"),
+		"Total size of the temporary files currently owned by backends of the user and application",
+		[]string{"usename", "application_name"},
+		prometheus.Labels{},
+	)
+
+	// pg_global never holds temporary files.
+	tempFilesTablespaceQuery = `
+	SELECT
+		t.spcname,
+		count(f.name) AS count,
+		COALESCE(sum(f.size), 0) AS size_bytes,
+		COALESCE(max(f.size), 0) AS largest_size_bytes
+	FROM pg_catalog.pg_tablespace t
+	LEFT JOIN LATERAL pg_ls_tmpdir(t.oid) f ON true
+	WHERE t.spcname <> 'pg_global'
+	GROUP BY t.spcname
+	ORDER BY t.spcname
+	`
+
+	// Temporary files are named pgsql_tmp<pid>.<n> after the backend that
+	// created them. Files of backends that have exited are reported with
+	// empty labels.
+	tempFilesBackendQuery = `
+	SELECT
+		COALESCE(a.usename, '') AS usename,
+		COALESCE(a.application_name, '') AS application_name,
+		count(*) AS count,
+		sum(f.size) AS size_bytes
+	FROM pg_catalog.pg_tablespace t
+	CROSS JOIN LATERAL pg_ls_tmpdir(t.oid) f
+	LEFT JOIN pg_catalog.pg_stat_activity a
+		ON a.pid = substring(f.name FROM '^pgsql_tmp([0-9]+)')::integer
+	WHERE t.spcname <> 'pg_global'
+	GROUP BY 1, 2
+	ORDER BY 1, 2
+	`
+)
+
+func (c *PGTempFilesCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("12.0.0")) {
+		c.log.Warn("temp_files collector is not available on PostgreSQL < 12.0.0, skipping")
+		return nil
+	}
+
+	if err := c.updateTablespaces(ctx, instance, ch); err != nil {
+		return err
+	}
+	return c.updateBackends(ctx, instance, ch)
+}
+
+func (c *PGTempFilesCollector) updateTablespaces(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, tempFilesTablespaceQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tablespace string
+		var count, size, largestSize sql.NullFloat64
+		if err := rows.Scan(&tablespace, &count, &size, &largestSize); err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			tempFilesCountDesc,
+			prometheus.GaugeValue,
+			count.Float64,
+			tablespace,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			tempFilesSizeDesc,
+			prometheus.GaugeValue,
+			size.Float64,
+			tablespace,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			tempFilesLargestSizeDesc,
+			prometheus.GaugeValue,
+			largestSize.Float64,
+			tablespace,
+		)
+	}
+	return rows.Err()
+}
+
+func (c *PGTempFilesCollector) updateBackends(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx, tempFilesBackendQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var usename, applicationName string
+		var count, size sql.NullFloat64
+		if err := rows.Scan(&usename, &applicationName, &count, &size); err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			tempFilesBackendCountDesc,
+			prometheus.GaugeValue,
+			count.Float64,
+			usename, applicationName,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			tempFilesBackendSizeDesc,
+			prometheus.GaugeValue,
+			size.Float64,
+			usename, applicationName,
+		)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,56 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestPGTempFilesCollector(t *testing.T) {
+	tablespaceRows := sqlmock.NewRows([]string{"spcname", "count", "size_bytes", "largest_size_bytes"}).
+		AddRow("fast_ssd", 0, 0, 0).
+		AddRow("pg_default", 3, 3221225472, 1073741824)
+	backendRows := sqlmock.NewRows([]string{"usename", "application_name", "count", "size_bytes"}).
+		AddRow("", "", 1, 1048576).
+		AddRow("reporting", "metabase", 2, 3220176896)
+
+	exited := labelMap{"usename": "", "application_name": ""}
+	reporting := labelMap{"usename": "reporting", "application_name": "metabase"}
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGTempFilesCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(tempFilesTablespaceQuery)).WillReturnRows(tablespaceRows)
+		mock.ExpectQuery(regexp.QuoteMeta(tempFilesBackendQuery)).WillReturnRows(backendRows)
+	}, []MetricResult{
+		{labels: labelMap{"tablespace": "fast_ssd"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"tablespace": "fast_ssd"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"tablespace": "fast_ssd"}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{"tablespace": "pg_default"}, metricType: dto.MetricType_GAUGE, value: 3},
+		{labels: labelMap{"tablespace": "pg_default"}, metricType: dto.MetricType_GAUGE, value: 3221225472},
+		{labels: labelMap{"tablespace": "pg_default"}, metricType: dto.MetricType_GAUGE, value: 1073741824},
+		{labels: exited, metricType: dto.MetricType_GAUGE, value: 1},
+		{labels: exited, metricType: dto.MetricType_GAUGE, value: 1048576},
+		{labels: reporting, metricType: dto.MetricType_GAUGE, value: 2},
+		{labels: reporting, metricType: dto.MetricType_GAUGE, value: 3220176896},
+	})
+}
+
+func TestPGTempFilesCollectorBefore12(t *testing.T) {
+	inst := &instance{version: semver.MustParse("11.0.0")}
+	testCollectorUpdate(t, &PGTempFilesCollector{log: promslog.NewNopLogger()}, inst, nil, nil)
+}
@@ -0,0 +1,125 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	// WARNING:
+	//   Disabled by default because pg_ls_archive_statusdir() requires
+	//   superuser or pg_monitor, and is only available from Postgres 12
+	registerCollector(walArchiveSubsystem, NewPGWALArchiveCollector)
+}
+
+type PGWALArchiveCollector struct {
+	log *slog.Logger
+}
+
+func NewPGWALArchiveCollector(config collectorConfig) (Collector, error) {
+	return &PGWALArchiveCollector{log: config.logger}, nil
+}
+
+var (
+	walArchiveReadyFilesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, walArchiveSubsystem, "ready_files"),
+		"Number of WAL files waiting to be archived",
+		[]string{},
+		prometheus.Labels{},
+	)
+	walArchiveOldestReadyAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, walArchiveSubsystem, "oldest_ready_age_seconds"),
+		"Time since the oldest WAL file waiting to be archived was marked ready, 0 when none is waiting",
+		[]string{},
+		prometheus.Labels{},
+	)
+	walArchiveLagDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, walArchiveSubsystem, "lag_bytes"),
+		"Amount of WAL between the end of the last archived segment and the current WAL insert location",
+		[]string{},
+		prometheus.Labels{},
+	)
+
+	// The end of the last archived segment is computed from its file name,
+	// which holds the high 32 bits of the LSN and the segment number within
+	// them. History and backup history files have no position and leave the
+	// lag NULL. Standbys archiving with archive_mode=always compare with the
+	// received WAL instead.
+	walArchiveQuery = `
+	WITH ready AS (
+		SELECT
+			count(*) AS files,
+			min(modification) AS oldest
+		FROM pg_ls_archive_statusdir()
+		WHERE name LIKE '%.ready'
+	), archived AS (
+		SELECT
+			('x' || substr(last_archived_wal, 9, 8))::bit(32)::bigint * 4294967296
+				+ (('x' || substr(last_archived_wal, 17, 8))::bit(32)::bigint + 1) * pg_size_bytes(current_setting('wal_segment_size')) AS end_lsn
+		FROM pg_stat_archiver
+		WHERE last_archived_wal ~ '^[0-9A-F]{24}($|\.partial$)'
+	)
+	SELECT
+		ready.files AS ready_files,
+		COALESCE(EXTRACT(EPOCH FROM now() - ready.oldest), 0) AS oldest_ready_age_seconds,
+		GREATEST(
+			(CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_insert_lsn() END - '0/0') - archived.end_lsn,
+			0
+		) AS lag_bytes
+	FROM ready
+	LEFT JOIN archived ON true
+	`
+)
+
+func (c *PGWALArchiveCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	if instance.version.LT(semver.MustParse("12.0.0")) {
+		c.log.Warn("wal_archive collector is not available on PostgreSQL < 12.0.0, skipping")
+		return nil
+	}
+
+	db := instance.getDB()
+	row := db.QueryRowContext(ctx, walArchiveQuery)
+
+	var readyFiles, oldestReadyAge, lag sql.NullFloat64
+	if err := row.Scan(&readyFiles, &oldestReadyAge, &lag); err != nil {
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		walArchiveReadyFilesDesc,
+		prometheus.GaugeValue,
+		readyFiles.Float64,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		walArchiveOldestReadyAgeDesc,
+		prometheus.GaugeValue,
+		oldestReadyAge.Float64,
+	)
+	// Nothing has been archived yet, or the last archived file was a
+	// history file.
+	if lag.Valid {
+		ch <- prometheus.MustNewConstMetric(
+			walArchiveLagDesc,
+			prometheus.GaugeValue,
+			lag.Float64,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package collector
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blang/semver/v4"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestPGWALArchiveCollector(t *testing.T) {
+	rows := sqlmock.NewRows([]string{"ready_files", "oldest_ready_age_seconds", "lag_bytes"}).
+		AddRow(42, 3600.5, 704643072)
+
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGWALArchiveCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(walArchiveQuery)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 42},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 3600.5},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 704643072},
+	})
+}
+
+func TestPGWALArchiveCollectorNothingArchived(t *testing.T) {
+	rows := sqlmock.NewRows([]string{"ready_files", "oldest_ready_age_seconds", "lag_bytes"}).
+		AddRow(0, 0, nil)
+
+	inst := &instance{version: semver.MustParse("16.0.0")}
+	testCollectorUpdate(t, &PGWALArchiveCollector{log: promslog.NewNopLogger()}, inst, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(walArchiveQuery)).WillReturnRows(rows)
+	}, []MetricResult{
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 0},
+		{labels: labelMap{}, metricType: dto.MetricType_GAUGE, value: 0},
+	})
+}
+
+func TestPGWALArchiveCollectorBefore12(t *testing.T) {
+	inst := &instance{version: semver.MustParse("11.0.0")}
+	testCollectorUpdate(t, &PGWALArchiveCollector{log: promslog.NewNopLogger()}, inst, nil, nil)
+}
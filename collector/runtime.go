@@ -55,6 +55,15 @@ func NewRuntime(validatedConfig config.ValidatedConfig, logger *slog.Logger) (*R
 		WithCollectionTimeout(cfg.CollectionTimeout.String()),
 		WithCollectorStates(cfg.Collectors),
 		WithPGStatStatementsConfig(cfg.PGStatStatements),
+		WithPGStatUserFunctionsConfig(cfg.PGStatUserFunctions),
+		WithPreparedXactsConfig(cfg.PreparedXacts),
+		WithSequencesConfig(cfg.Sequences),
+		WithSettingsConfig(cfg.Settings),
+		WithBloatConfig(cfg.Bloat),
+		WithIndexHealthConfig(cfg.IndexHealth),
+		WithTableWraparoundConfig(cfg.TableWraparound),
+		WithTablespaceConfig(cfg.Tablespace),
+		WithCredentialFiles(cfg.CredentialFiles),
 	)
 	if err != nil {
 		runtime.Close()
@@ -93,5 +102,6 @@ func exporterOptions(cfg config.Config) []exporter.ExporterOpt {
 		exporter.ExcludeDatabases(cfg.ExcludeDatabases),
 		exporter.IncludeDatabases(strings.Join(cfg.IncludeDatabases, ",")),
 		exporter.WithMetricPrefix(cfg.MetricPrefix),
+		exporter.WithCredentialFiles(cfg.CredentialFiles),
 	}
 }
@@ -0,0 +1,189 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+)
+
+const (
+	// awsIAMTokenLifetime is fixed by RDS, tokens are only accepted for 15
+	// minutes after they were signed.
+	awsIAMTokenLifetime = 15 * time.Minute
+	// awsIAMTokenRefreshAge is the age at which a cached token is replaced.
+	awsIAMTokenRefreshAge = 10 * time.Minute
+)
+
+// AWSIAMAuth authenticates to RDS and Aurora with an IAM authentication token
+// instead of a password. Tokens are generated locally with the AWS SDK from
+// its default credential chain and always sent over TLS.
+type AWSIAMAuth struct {
+	Username string `yaml:"username"`
+	Region   string `yaml:"region"`
+	Profile  string `yaml:"profile"`
+}
+
+type awsIAMToken struct {
+	token    string
+	signed   time.Time
+	provider aws.CredentialsProvider
+}
+
+var (
+	awsIAMTokensMu sync.Mutex
+	awsIAMTokens   = map[string]awsIAMToken{}
+)
+
+func (a AWSIAMAuth) validate(options map[string]string) error {
+	if a.Username == "" {
+		return errors.New("aws_iam: username must be set")
+	}
+	if a.region() == "" {
+		return errors.New("aws_iam: region must be set, either in the module or through AWS_REGION")
+	}
+	if sslMode, ok := options["sslmode"]; ok && !isTLSSSLMode(sslMode) {
+		return fmt.Errorf("aws_iam: sslmode %q is not allowed, IAM authentication requires TLS", sslMode)
+	}
+	return nil
+}
+
+func (a AWSIAMAuth) region() string {
+	if a.Region != "" {
+		return a.Region
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// configure sets the user and a current token as the password of the dsn.
+func (a AWSIAMAuth) configure(dsn *DSN) error {
+	endpoint, sslMode, err := awsIAMTarget(*dsn)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	region := a.region()
+	provider, err := awsCredentialsProvider(ctx, a.Profile, region)
+	if err != nil {
+		return fmt.Errorf("aws_iam: %w", err)
+	}
+	token, err := cachedRDSAuthToken(ctx, endpoint, region, a.Username, provider, time.Now())
+	if err != nil {
+		return fmt.Errorf("aws_iam: %w", err)
+	}
+
+	dsn.username = a.Username
+	dsn.password = token
+	if !isTLSSSLMode(sslMode) {
+		dsn.query.Set("sslmode", "require")
+	}
+	return nil
+}
+
+// awsIAMTarget returns host:port as it has to be signed into the token, and
+// the sslmode of the target. A target naming a service is resolved the way it
+// is connected to.
+func awsIAMTarget(dsn DSN) (string, string, error) {
+	host, sslMode := dsn.host, dsn.query.Get("sslmode")
+	if dsn.query.Has("service") {
+		cfg, err := DriverConfig(dsn.GetConnectionString())
+		if err != nil {
+			return "", "", fmt.Errorf("aws_iam: %w", err)
+		}
+		host = net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port)))
+		sslMode = string(cfg.SSLMode)
+	}
+	if host == "" {
+		return "", "", errors.New("aws_iam: target must include a host")
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host, sslMode, nil
+	}
+	return net.JoinHostPort(host, "5432"), sslMode, nil
+}
+
+// cachedRDSAuthToken returns a token for endpoint and user, reusing a cached
+// one while it is younger than awsIAMTokenRefreshAge. The cache is keyed by
+// access key as well, so refreshed temporary credentials get a new token.
+func cachedRDSAuthToken(ctx context.Context, endpoint, region, user string, provider aws.CredentialsProvider, now time.Time) (string, error) {
+	creds, err := provider.Retrieve(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	awsIAMTokensMu.Lock()
+	defer awsIAMTokensMu.Unlock()
+
+	key := endpoint + "|" + region + "|" + user + "|" + creds.AccessKeyID
+	if cached, ok := awsIAMTokens[key]; ok && now.Sub(cached.signed) < awsIAMTokenRefreshAge {
+		return cached.token, nil
+	}
+
+	for k, cached := range awsIAMTokens {
+		if now.Sub(cached.signed) >= awsIAMTokenLifetime {
+			delete(awsIAMTokens, k)
+		}
+	}
+	// Signing is local, the credentials are the ones just retrieved.
+	token, err := auth.BuildAuthToken(ctx, endpoint, region, user, credentials.StaticCredentialsProvider{Value: creds})
+	if err != nil {
+		return "", err
+	}
+	awsIAMTokens[key] = awsIAMToken{token: token, signed: now, provider: provider}
+	return token, nil
+}
+
+// forgetRDSAuthToken drops token from the cache, and has the credentials it
+// was signed with fetched again, after the server rejected it. An empty token
+// drops every cached token and credential provider.
+func forgetRDSAuthToken(token string) {
+	awsIAMTokensMu.Lock()
+	var providers []aws.CredentialsProvider
+	for k, cached := range awsIAMTokens {
+		if token == "" || cached.token == token {
+			providers = append(providers, cached.provider)
+			delete(awsIAMTokens, k)
+		}
+	}
+	awsIAMTokensMu.Unlock()
+
+	if token == "" {
+		forgetAWSCredentialsProviders()
+		return
+	}
+	for _, provider := range providers {
+		invalidateAWSCredentials(provider)
+	}
+}
+
+func isTLSSSLMode(sslMode string) bool {
+	switch sslMode {
+	case "require", "verify-ca", "verify-full":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,399 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// clearAWSEnv unsets every variable the credential chain looks at, so tests
+// do not pick up the credentials of the machine running them.
+func clearAWSEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE", "AWS_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_ROLE_SESSION_NAME", "AWS_ENDPOINT_URL_STS",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("HOME", t.TempDir())
+	resetCredentialCaches()
+	t.Cleanup(resetCredentialCaches)
+}
+
+// countingCredentials counts how often credentials are fetched, and returns a
+// new access key every time.
+type countingCredentials struct {
+	calls atomic.Int32
+}
+
+func (c *countingCredentials) Retrieve(context.Context) (aws.Credentials, error) {
+	calls := c.calls.Add(1)
+	return aws.Credentials{AccessKeyID: fmt.Sprintf("AKID-forget-%d", calls), SecretAccessKey: "SECRET"}, nil
+}
+
+func TestCachedRDSAuthToken(t *testing.T) {
+	provider := credentials.NewStaticCredentialsProvider("AKID-cache", "SECRET", "SESSION")
+	now := time.Now()
+
+	first, err := cachedRDSAuthToken(context.Background(), "cache.example.com:5432", "eu-west-1", "exporter", provider, now)
+	if err != nil {
+		t.Fatalf("cachedRDSAuthToken() error = %v", err)
+	}
+	endpoint, rawQuery, ok := strings.Cut(first, "?")
+	if !ok || endpoint != "cache.example.com:5432" {
+		t.Fatalf("token = %q, want it to start with the endpoint", first)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	for key, want := range map[string]string{
+		"Action":               "connect",
+		"DBUser":               "exporter",
+		"X-Amz-Expires":        "900",
+		"X-Amz-Security-Token": "SESSION",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("query[%q] = %q, want %q", key, got, want)
+		}
+	}
+	if got := query.Get("X-Amz-Credential"); !strings.HasPrefix(got, "AKID-cache/") || !strings.HasSuffix(got, "/eu-west-1/rds-db/aws4_request") {
+		t.Errorf("X-Amz-Credential = %q, want a rds-db scope in eu-west-1", got)
+	}
+
+	if got, _ := cachedRDSAuthToken(context.Background(), "cache.example.com:5432", "eu-west-1", "exporter", provider, now.Add(time.Minute)); got != first {
+		t.Fatal("token was not reused within its refresh age")
+	}
+	// The token is signed again, possibly within the same second.
+	refreshed := now.Add(awsIAMTokenRefreshAge)
+	if _, err := cachedRDSAuthToken(context.Background(), "cache.example.com:5432", "eu-west-1", "exporter", provider, refreshed); err != nil {
+		t.Fatalf("cachedRDSAuthToken() error = %v", err)
+	}
+	awsIAMTokensMu.Lock()
+	signed := awsIAMTokens["cache.example.com:5432|eu-west-1|exporter|AKID-cache"].signed
+	awsIAMTokensMu.Unlock()
+	if !signed.Equal(refreshed) {
+		t.Fatal("token was reused past its refresh age")
+	}
+	rotated := credentials.NewStaticCredentialsProvider("AKID-rotated", "SECRET", "")
+	if got, _ := cachedRDSAuthToken(context.Background(), "cache.example.com:5432", "eu-west-1", "exporter", rotated, now); got == first {
+		t.Fatal("token was reused for different credentials")
+	}
+}
+
+func TestForgetRDSAuthToken(t *testing.T) {
+	source := &countingCredentials{}
+	provider := aws.NewCredentialsCache(source)
+	now := time.Now()
+
+	first, err := cachedRDSAuthToken(context.Background(), "forget.example.com:5432", "eu-west-1", "exporter", provider, now)
+	if err != nil {
+		t.Fatalf("cachedRDSAuthToken() error = %v", err)
+	}
+	forgetRDSAuthToken("some other token")
+	if got, _ := cachedRDSAuthToken(context.Background(), "forget.example.com:5432", "eu-west-1", "exporter", provider, now); got != first {
+		t.Fatal("token was dropped after a different token was rejected")
+	}
+	if calls := source.calls.Load(); calls != 1 {
+		t.Fatalf("credentials were fetched %d times, want them to be cached", calls)
+	}
+
+	forgetRDSAuthToken(first)
+	if got, _ := cachedRDSAuthToken(context.Background(), "forget.example.com:5432", "eu-west-1", "exporter", provider, now.Add(time.Second)); got == first {
+		t.Fatal("token was reused after the server rejected it")
+	}
+	if calls := source.calls.Load(); calls != 2 {
+		t.Fatalf("credentials were fetched %d times, want them to be fetched again after the token was rejected", calls)
+	}
+}
+
+func TestAWSIAMConfigureTarget(t *testing.T) {
+	clearAWSEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+
+	module := AuthModule{
+		Type:   AuthModuleTypeAWSIAM,
+		AWSIAM: AWSIAMAuth{Username: "exporter", Region: "us-east-1"},
+	}
+	dsn, err := module.ConfigureTarget("postgresql://db.example.com/postgres?sslmode=disable")
+	if err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+	if got, want := dsn.username, "exporter"; got != want {
+		t.Fatalf("username = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(dsn.password, "db.example.com:5432?") {
+		t.Fatalf("password = %q, want a token for db.example.com:5432", dsn.password)
+	}
+	if got, want := dsn.query.Get("sslmode"), "require"; got != want {
+		t.Fatalf("sslmode = %q, want %q", got, want)
+	}
+}
+
+func TestAWSIAMConfigureTargetService(t *testing.T) {
+	clearAWSEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	serviceFile := filepath.Join(t.TempDir(), "pg_service.conf")
+	if err := os.WriteFile(serviceFile, []byte("[rds]\nhost=rds.example.com\nport=6432\nsslmode=verify-full\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGSERVICE", "")
+	t.Setenv("PGSERVICEFILE", serviceFile)
+
+	module := AuthModule{
+		Type:   AuthModuleTypeAWSIAM,
+		AWSIAM: AWSIAMAuth{Username: "exporter", Region: "us-east-1"},
+	}
+	dsn, err := module.ConfigureTarget("service=rds")
+	if err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+	if !strings.HasPrefix(dsn.password, "rds.example.com:6432?") {
+		t.Fatalf("password = %q, want a token for the host of the service", dsn.password)
+	}
+	if dsn.query.Has("sslmode") {
+		t.Fatalf("sslmode = %q, want the verify-full of the service to be kept", dsn.query.Get("sslmode"))
+	}
+}
+
+func TestAWSIAMValidate(t *testing.T) {
+	clearAWSEnv(t)
+
+	tests := []struct {
+		name    string
+		module  AuthModule
+		wantErr string
+	}{
+		{
+			name:    "missing username",
+			module:  AuthModule{Type: AuthModuleTypeAWSIAM, AWSIAM: AWSIAMAuth{Region: "us-east-1"}},
+			wantErr: "aws_iam: username must be set",
+		},
+		{
+			name:    "missing region",
+			module:  AuthModule{Type: AuthModuleTypeAWSIAM, AWSIAM: AWSIAMAuth{Username: "exporter"}},
+			wantErr: "aws_iam: region must be set",
+		},
+		{
+			name: "plaintext",
+			module: AuthModule{
+				Type:    AuthModuleTypeAWSIAM,
+				AWSIAM:  AWSIAMAuth{Username: "exporter", Region: "us-east-1"},
+				Options: map[string]string{"sslmode": "disable"},
+			},
+			wantErr: `aws_iam: sslmode "disable" is not allowed`,
+		},
+		{
+			name:   "valid",
+			module: AuthModule{Type: AuthModuleTypeAWSIAM, AWSIAM: AWSIAMAuth{Username: "exporter", Region: "us-east-1"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.module.validate()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("validate() error = %v, want it to contain %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// retrieveAWSCredentials fetches the credentials of the chain for profile.
+func retrieveAWSCredentials(ctx context.Context, profile string) (aws.Credentials, error) {
+	provider, err := awsCredentialsProvider(ctx, profile, "us-east-1")
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	return provider.Retrieve(ctx)
+}
+
+func TestAWSCredentialsProviderSharedFile(t *testing.T) {
+	clearAWSEnv(t)
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte(`
+[default]
+aws_access_key_id = DEFAULT
+aws_secret_access_key = default-secret
+
+[monitoring]
+aws_access_key_id = MONITORING
+aws_secret_access_key = monitoring-secret
+aws_session_token = monitoring-session
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+
+	creds, err := retrieveAWSCredentials(context.Background(), "monitoring")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if creds.AccessKeyID != "MONITORING" || creds.SecretAccessKey != "monitoring-secret" || creds.SessionToken != "monitoring-session" {
+		t.Fatalf("Retrieve() = %+v, want the monitoring profile", creds)
+	}
+
+	resetCredentialCaches()
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	creds, err = retrieveAWSCredentials(context.Background(), "monitoring")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if creds.AccessKeyID != "MONITORING" {
+		t.Fatalf("AccessKeyID = %q, want the configured profile to take precedence", creds.AccessKeyID)
+	}
+	creds, err = retrieveAWSCredentials(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if creds.AccessKeyID != "ENV" {
+		t.Fatalf("AccessKeyID = %q, want environment credentials to take precedence over the default profile", creds.AccessKeyID)
+	}
+
+	if _, err := retrieveAWSCredentials(context.Background(), "missing"); err == nil {
+		t.Fatal("awsCredentialsProvider() error = nil, want error for a profile that is not in the file")
+	}
+}
+
+// fakeSTS answers AssumeRoleWithWebIdentity, after release is closed if it is
+// set. Every request is sent on requests.
+func fakeSTS(t *testing.T, requests chan<- struct{}, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		if got, want := r.Form.Get("WebIdentityToken"), "jwt"; got != want {
+			t.Errorf("WebIdentityToken = %q, want %q", got, want)
+		}
+		requests <- struct{}{}
+		if release != nil {
+			<-release
+		}
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIA</AccessKeyId>
+      <SecretAccessKey>web-secret</SecretAccessKey>
+      <SessionToken>web-session</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	t.Cleanup(sts.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("jwt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/exporter")
+	t.Setenv("AWS_ENDPOINT_URL_STS", sts.URL)
+	return sts
+}
+
+func TestAWSCredentialsProviderWebIdentity(t *testing.T) {
+	clearAWSEnv(t)
+	requests := make(chan struct{}, 10)
+	fakeSTS(t, requests, nil)
+
+	for range 2 {
+		creds, err := retrieveAWSCredentials(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+		if creds.AccessKeyID != "ASIA" || creds.SessionToken != "web-session" {
+			t.Fatalf("Retrieve() = %+v, want the STS credentials", creds)
+		}
+	}
+	if len(requests) != 1 {
+		t.Fatalf("STS was called %d times, want the credentials to be cached", len(requests))
+	}
+
+	resetCredentialCaches()
+	if _, err := retrieveAWSCredentials(context.Background(), ""); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("STS was called %d times, want the cache to be dropped on reset", len(requests))
+	}
+}
+
+// A slow STS only holds up the modules that need its credentials.
+func TestAWSCredentialsProviderSlowWebIdentity(t *testing.T) {
+	clearAWSEnv(t)
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte("[monitoring]\naws_access_key_id = MONITORING\naws_secret_access_key = monitoring-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	requests, release := make(chan struct{}, 10), make(chan struct{})
+	fakeSTS(t, requests, release)
+
+	done := make(chan error)
+	go func() {
+		_, err := retrieveAWSCredentials(context.Background(), "")
+		done <- err
+	}()
+	<-requests
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	creds, err := retrieveAWSCredentials(ctx, "monitoring")
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v, want it not to wait for STS", err)
+	}
+	if creds.AccessKeyID != "MONITORING" {
+		t.Fatalf("AccessKeyID = %q, want the monitoring profile", creds.AccessKeyID)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+}
+
+func TestAWSCredentialsProviderNone(t *testing.T) {
+	clearAWSEnv(t)
+	if _, err := retrieveAWSCredentials(context.Background(), ""); err == nil {
+		t.Fatal("Retrieve() error = nil, want error")
+	}
+}
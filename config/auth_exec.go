@@ -0,0 +1,217 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultExecAuthTimeout = 10 * time.Second
+	// execCredentialsRefreshMargin is how long before expires_at cached
+	// credentials are no longer used.
+	execCredentialsRefreshMargin = 30 * time.Second
+	// execStderrLimit caps how much of the command's stderr ends up in errors.
+	execStderrLimit = 512
+)
+
+// ExecAuth runs a command to obtain credentials, like kubectl credential
+// plugins do. The command gets the probe target in its environment and must
+// print a JSON object with username, password and an optional RFC 3339
+// expires_at to stdout. Credentials with an expiry are cached until shortly
+// before it, credentials without one are fetched for every probe. Cached
+// credentials are dropped when the server rejects them or the configuration
+// is reloaded.
+type ExecAuth struct {
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+type execCredentials struct {
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var (
+	execCredentialsMu    sync.Mutex
+	execCredentialsCache = map[string]execCredentials{}
+	// execCredentialsGeneration is increased when the cache is reset, so
+	// runs started before do not store credentials of an old configuration.
+	execCredentialsGeneration uint64
+	execCredentialsGroup      singleflight.Group
+
+	execRunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "postgres_exporter",
+		Subsystem: "auth_exec",
+		Name:      "runs_total",
+		Help:      "Number of times an exec auth module command was run.",
+	}, []string{"auth_module"})
+	execFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "postgres_exporter",
+		Subsystem: "auth_exec",
+		Name:      "failures_total",
+		Help:      "Number of exec auth module command runs that did not produce credentials.",
+	}, []string{"auth_module"})
+)
+
+func (e ExecAuth) validate() error {
+	if e.Command == "" {
+		return errors.New("exec: command must be set")
+	}
+	if _, err := exec.LookPath(e.Command); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	if e.Timeout < 0 {
+		return errors.New("exec: timeout must not be negative")
+	}
+	return nil
+}
+
+// configure sets the credentials for target on the dsn, running the command
+// unless there are cached credentials that have not expired. Concurrent
+// probes of the same module and target share a single run.
+func (e ExecAuth) configure(dsn *DSN, module, target string) error {
+	cacheKey := module + "|" + target
+
+	execCredentialsMu.Lock()
+	creds, ok := execCredentialsCache[cacheKey]
+	generation := execCredentialsGeneration
+	execCredentialsMu.Unlock()
+	if !ok || time.Until(creds.ExpiresAt) <= execCredentialsRefreshMargin {
+		v, err, _ := execCredentialsGroup.Do(fmt.Sprintf("%d|%s", generation, cacheKey), func() (any, error) {
+			creds, err := e.run(module, target, *dsn)
+			if err != nil {
+				return nil, err
+			}
+			execCredentialsMu.Lock()
+			defer execCredentialsMu.Unlock()
+			if generation != execCredentialsGeneration {
+				return creds, nil
+			}
+			if creds.ExpiresAt.IsZero() {
+				delete(execCredentialsCache, cacheKey)
+			} else {
+				execCredentialsCache[cacheKey] = creds
+			}
+			return creds, nil
+		})
+		if err != nil {
+			return err
+		}
+		creds = v.(execCredentials)
+	}
+
+	if creds.Username != "" {
+		dsn.username = creds.Username
+	}
+	dsn.password = creds.Password
+	return nil
+}
+
+// forgetExecCredentials drops the cached credentials with the given password,
+// or all of them if it is empty.
+func forgetExecCredentials(password string) {
+	execCredentialsMu.Lock()
+	defer execCredentialsMu.Unlock()
+
+	if password == "" {
+		clear(execCredentialsCache)
+		execCredentialsGeneration++
+		return
+	}
+	for k, creds := range execCredentialsCache {
+		if creds.Password == password {
+			delete(execCredentialsCache, k)
+		}
+	}
+}
+
+func (e ExecAuth) run(module, target string, dsn DSN) (execCredentials, error) {
+	execRunsTotal.WithLabelValues(module).Inc()
+	creds, err := e.exec(target, dsn)
+	if err != nil {
+		execFailuresTotal.WithLabelValues(module).Inc()
+		return execCredentials{}, fmt.Errorf("exec: %s: %w", e.Command, err)
+	}
+	return creds, nil
+}
+
+func (e ExecAuth) exec(target string, dsn DSN) (execCredentials, error) {
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = defaultExecAuthTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	database := strings.TrimPrefix(dsn.path, "/")
+	if dbname := dsn.query.Get("dbname"); dbname != "" {
+		database = dbname
+	}
+
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	// Do not wait for children of a killed command that still hold stdout.
+	cmd.WaitDelay = time.Second
+	cmd.Env = os.Environ()
+	for k, v := range e.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Env = append(cmd.Env,
+		"PG_EXPORTER_TARGET="+target,
+		"PG_EXPORTER_TARGET_HOST="+dsn.host,
+		"PG_EXPORTER_TARGET_DATABASE="+database,
+		"PG_EXPORTER_TARGET_USER="+dsn.username,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return execCredentials{}, fmt.Errorf("timed out after %s", timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > execStderrLimit {
+			msg = msg[:execStderrLimit] + "..."
+		}
+		if msg != "" {
+			return execCredentials{}, fmt.Errorf("%w: %s", err, msg)
+		}
+		return execCredentials{}, err
+	}
+
+	var creds execCredentials
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return execCredentials{}, fmt.Errorf("parsing output: %w", err)
+	}
+	if creds.Password == "" {
+		return execCredentials{}, errors.New("output contains no password")
+	}
+	return creds, nil
+}
@@ -0,0 +1,214 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// writeExecScript writes a shell script to a temporary directory and returns
+// its path.
+func writeExecScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "credentials.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecAuthConfigureTarget(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := writeExecScript(t, `echo run >> "`+calls+`"
+printf '{"username": "%s", "password": "secret-%s", "expires_at": "%s"}' "$PREFIX-user" "$PG_EXPORTER_TARGET_DATABASE" "`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"
+`)
+
+	module := AuthModule{
+		Type: AuthModuleTypeExec,
+		Exec: ExecAuth{Command: script, Env: map[string]string{"PREFIX": "exec"}},
+		name: "exec-cached",
+	}
+	if err := module.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+
+	for range 2 {
+		dsn, err := module.ConfigureTarget("postgresql://db.example.com:5432/orders")
+		if err != nil {
+			t.Fatalf("ConfigureTarget() error = %v", err)
+		}
+		if dsn.username != "exec-user" || dsn.password != "secret-orders" {
+			t.Fatalf("credentials = %q/%q, want exec-user/secret-orders", dsn.username, dsn.password)
+		}
+	}
+
+	out, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(out), "run"); got != 1 {
+		t.Fatalf("command ran %d times, want the credentials to be cached", got)
+	}
+}
+
+func TestExecAuthConcurrentProbesRunOnce(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := writeExecScript(t, `echo run >> "`+calls+`"
+sleep 0.5
+echo '{"password": "secret", "expires_at": "`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"}'
+`)
+	module := AuthModule{Type: AuthModuleTypeExec, Exec: ExecAuth{Command: script}, name: "exec-concurrent"}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			if _, err := module.ConfigureTarget("postgresql://exporter@db.example.com:5432/postgres"); err != nil {
+				t.Errorf("ConfigureTarget() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	out, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(out), "run"); got != 1 {
+		t.Fatalf("command ran %d times, want concurrent probes to share one run", got)
+	}
+}
+
+func TestExecAuthRejectedCredentialsAreDropped(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := writeExecScript(t, `echo run >> "`+calls+`"
+echo '{"password": "rejected", "expires_at": "`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"}'
+`)
+	module := AuthModule{Type: AuthModuleTypeExec, Exec: ExecAuth{Command: script}, name: "exec-rejected"}
+	target := "postgresql://exporter@db.example.com:5432/postgres"
+
+	dsn, err := module.ConfigureTarget(target)
+	if err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+	forgetRejectedCredentials(dsn.GetConnectionString())
+	if _, err := module.ConfigureTarget(target); err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+	resetCredentialCaches()
+	if _, err := module.ConfigureTarget(target); err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+
+	out, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(out), "run"); got != 3 {
+		t.Fatalf("command ran %d times, want the cache to be dropped on rejection and on reset", got)
+	}
+}
+
+func TestExecAuthWithoutExpiryIsNotCached(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := writeExecScript(t, `echo run >> "`+calls+`"
+echo '{"password": "secret"}'
+`)
+	module := AuthModule{Type: AuthModuleTypeExec, Exec: ExecAuth{Command: script}, name: "exec-uncached"}
+
+	for range 2 {
+		dsn, err := module.ConfigureTarget("postgresql://exporter@db.example.com:5432/postgres")
+		if err != nil {
+			t.Fatalf("ConfigureTarget() error = %v", err)
+		}
+		if dsn.username != "exporter" || dsn.password != "secret" {
+			t.Fatalf("credentials = %q/%q, want exporter/secret", dsn.username, dsn.password)
+		}
+	}
+
+	out, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(out), "run"); got != 2 {
+		t.Fatalf("command ran %d times, want 2", got)
+	}
+}
+
+func TestExecAuthFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		auth   func(string) ExecAuth
+		want   string
+	}{
+		{
+			name:   "non-zero exit",
+			script: "echo 'vault is sealed' >&2\nexit 3\n",
+			want:   "exit status 3: vault is sealed",
+		},
+		{
+			name:   "invalid output",
+			script: "echo not json\n",
+			want:   "parsing output",
+		},
+		{
+			name:   "missing password",
+			script: "echo '{\"username\": \"exporter\"}'\n",
+			want:   "output contains no password",
+		},
+		{
+			name:   "timeout",
+			script: "sleep 5\n",
+			auth: func(script string) ExecAuth {
+				return ExecAuth{Command: script, Timeout: 50 * time.Millisecond}
+			},
+			want: "timed out after 50ms",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script := writeExecScript(t, test.script)
+			auth := ExecAuth{Command: script}
+			if test.auth != nil {
+				auth = test.auth(script)
+			}
+			module := AuthModule{Type: AuthModuleTypeExec, Exec: auth, name: "exec-" + test.name}
+
+			_, err := module.ConfigureTarget("db.example.com:5432")
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("ConfigureTarget() error = %v, want it to contain %q", err, test.want)
+			}
+			if got := testutil.ToFloat64(execFailuresTotal.WithLabelValues(module.name)); got != 1 {
+				t.Fatalf("failures_total = %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestExecAuthValidate(t *testing.T) {
+	if err := (ExecAuth{}).validate(); err == nil || err.Error() != "exec: command must be set" {
+		t.Fatalf("validate() error = %v, want missing command", err)
+	}
+	if err := (ExecAuth{Command: filepath.Join(t.TempDir(), "missing")}).validate(); err == nil {
+		t.Fatal("validate() error = nil for a missing command, want error")
+	}
+}
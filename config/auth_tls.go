@@ -0,0 +1,159 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const defaultTLSAuthSSLMode = "verify-full"
+
+// TLSAuth authenticates to the server with a client certificate. The files are
+// read again every time a target is configured, and the driver reads them on
+// every new connection, so rotated certificates are used without a restart.
+type TLSAuth struct {
+	Username        string `yaml:"username"`
+	SSLCert         string `yaml:"sslcert"`
+	SSLKey          string `yaml:"sslkey"`
+	SSLRootCert     string `yaml:"sslrootcert"`
+	SSLMode         string `yaml:"sslmode"`
+	SSLPasswordFile string `yaml:"sslpassword_file"`
+}
+
+func (t TLSAuth) validate() error {
+	if t.SSLCert == "" {
+		return errors.New("tls: sslcert must be set")
+	}
+	if t.SSLKey == "" {
+		return errors.New("tls: sslkey must be set")
+	}
+	if t.SSLMode != "" && !isTLSSSLMode(t.SSLMode) {
+		return fmt.Errorf("tls: unsupported sslmode %q, must be one of require, verify-ca or verify-full", t.SSLMode)
+	}
+
+	certPEM, keyPEM, err := t.loadKeyPair()
+	if err != nil {
+		return err
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return fmt.Errorf("tls: sslcert %q and sslkey %q do not form a valid key pair: %w", t.SSLCert, t.SSLKey, err)
+	}
+
+	if t.SSLRootCert != "" && t.SSLRootCert != "system" {
+		rootPEM, err := os.ReadFile(t.SSLRootCert)
+		if err != nil {
+			return fmt.Errorf("tls: reading sslrootcert: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(rootPEM) {
+			return fmt.Errorf("tls: sslrootcert %q contains no PEM encoded certificates", t.SSLRootCert)
+		}
+	}
+	return nil
+}
+
+// configure sets the TLS connection parameters on the dsn. Without a key
+// passphrase the file paths are passed to the driver as they are. An encrypted
+// key is decrypted here and passed inline together with the certificates,
+// because the driver cannot decrypt keys itself.
+func (t TLSAuth) configure(dsn *DSN) error {
+	if t.Username != "" {
+		dsn.username = t.Username
+	}
+	sslMode := t.SSLMode
+	if sslMode == "" {
+		sslMode = defaultTLSAuthSSLMode
+	}
+	dsn.query.Set("sslmode", sslMode)
+
+	if t.SSLPasswordFile == "" {
+		dsn.query.Set("sslcert", t.SSLCert)
+		dsn.query.Set("sslkey", t.SSLKey)
+		if t.SSLRootCert != "" {
+			dsn.query.Set("sslrootcert", t.SSLRootCert)
+		}
+		return nil
+	}
+
+	certPEM, keyPEM, err := t.loadKeyPair()
+	if err != nil {
+		return err
+	}
+	dsn.query.Set("sslinline", "true")
+	dsn.query.Set("sslcert", string(certPEM))
+	dsn.query.Set("sslkey", string(keyPEM))
+	if t.SSLRootCert != "" && t.SSLRootCert != "system" {
+		rootPEM, err := os.ReadFile(t.SSLRootCert)
+		if err != nil {
+			return fmt.Errorf("tls: reading sslrootcert: %w", err)
+		}
+		dsn.query.Set("sslrootcert", string(rootPEM))
+	} else if t.SSLRootCert != "" {
+		dsn.query.Set("sslrootcert", t.SSLRootCert)
+	}
+	return nil
+}
+
+// loadKeyPair reads the client certificate and key, decrypting the key with
+// the passphrase from SSLPasswordFile if one is configured.
+func (t TLSAuth) loadKeyPair() ([]byte, []byte, error) {
+	certPEM, err := os.ReadFile(t.SSLCert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: reading sslcert: %w", err)
+	}
+	keyPEM, err := os.ReadFile(t.SSLKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: reading sslkey: %w", err)
+	}
+	if t.SSLPasswordFile == "" {
+		return certPEM, keyPEM, nil
+	}
+
+	passphrase, err := os.ReadFile(t.SSLPasswordFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: reading sslpassword_file: %w", err)
+	}
+	keyPEM, err = decryptKeyPEM(keyPEM, []byte(strings.TrimRight(string(passphrase), "\r\n")))
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: decrypting sslkey %q: %w", t.SSLKey, err)
+	}
+	return certPEM, keyPEM, nil
+}
+
+// decryptKeyPEM decrypts a private key in the legacy OpenSSL encrypted PEM
+// format (Proc-Type: 4,ENCRYPTED). Unencrypted keys are returned unchanged.
+func decryptKeyPEM(keyPEM, passphrase []byte) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, errors.New("encrypted PKCS#8 keys are not supported, convert the key with `openssl rsa -aes256` or `openssl ec -aes256`")
+	}
+	//nolint:staticcheck // Deprecated, but the only stdlib support for encrypted PEM keys.
+	if !x509.IsEncryptedPEMBlock(block) {
+		return keyPEM, nil
+	}
+	//nolint:staticcheck // See above.
+	der, err := x509.DecryptPEMBlock(block, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+}
@@ -0,0 +1,209 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestKeyPair writes a self-signed certificate and its key to dir and
+// returns their paths. A non-empty passphrase encrypts the key.
+func writeTestKeyPair(t *testing.T, dir, name, passphrase string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	keyBlock := &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}
+	if passphrase != "" {
+		//nolint:staticcheck // Produces the legacy format under test.
+		keyBlock, err = x509.EncryptPEMBlock(rand.Reader, keyBlock.Type, keyDER, []byte(passphrase), x509.PEMCipherAES256)
+		if err != nil {
+			t.Fatalf("EncryptPEMBlock() error = %v", err)
+		}
+	}
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(keyBlock), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestDecodeAuthConfigTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestKeyPair(t, dir, "client", "")
+	rootPath, _ := writeTestKeyPair(t, dir, "root", "")
+
+	config, err := DecodeAuthConfig(strings.NewReader(fmt.Sprintf(`
+auth_modules:
+  cert:
+    type: tls
+    tls:
+      username: exporter
+      sslcert: %s
+      sslkey: %s
+      sslrootcert: %s
+`, certPath, keyPath, rootPath)))
+	if err != nil {
+		t.Fatalf("DecodeAuthConfig() error = %v", err)
+	}
+
+	dsn, err := config.AuthModules["cert"].ConfigureTarget("db.example.com:5432")
+	if err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+	if got, want := dsn.username, "exporter"; got != want {
+		t.Fatalf("username = %q, want %q", got, want)
+	}
+	for key, want := range map[string]string{
+		"sslmode":     "verify-full",
+		"sslcert":     certPath,
+		"sslkey":      keyPath,
+		"sslrootcert": rootPath,
+	} {
+		if got := dsn.query.Get(key); got != want {
+			t.Errorf("query[%q] = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestTLSAuthEncryptedKey(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestKeyPair(t, dir, "client", "secret")
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := TLSAuth{SSLCert: certPath, SSLKey: keyPath, SSLMode: "require", SSLPasswordFile: passwordFile}
+	if err := auth.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+
+	dsn, err := AuthModule{Type: AuthModuleTypeTLS, TLS: auth}.ConfigureTarget("db.example.com:5432")
+	if err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+	if got, want := dsn.query.Get("sslinline"), "true"; got != want {
+		t.Fatalf("query[sslinline] = %q, want %q", got, want)
+	}
+	if strings.Contains(dsn.query.Get("sslkey"), "ENCRYPTED") {
+		t.Fatal("sslkey is still encrypted")
+	}
+	if strings.Contains(dsn.String(), "PRIVATE KEY") {
+		t.Fatalf("String() leaks the inline key: %s", dsn.String())
+	}
+
+	if err := os.WriteFile(passwordFile, []byte("wrong"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.validate(); err == nil {
+		t.Fatal("validate() error = nil with wrong passphrase, want error")
+	}
+}
+
+func TestTLSAuthValidateFailures(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestKeyPair(t, dir, "client", "")
+	otherCertPath, _ := writeTestKeyPair(t, dir, "other", "")
+
+	tests := []struct {
+		name string
+		auth TLSAuth
+		want string
+	}{
+		{
+			name: "missing sslcert",
+			auth: TLSAuth{SSLKey: keyPath},
+			want: "tls: sslcert must be set",
+		},
+		{
+			name: "missing sslkey",
+			auth: TLSAuth{SSLCert: certPath},
+			want: "tls: sslkey must be set",
+		},
+		{
+			name: "weak sslmode",
+			auth: TLSAuth{SSLCert: certPath, SSLKey: keyPath, SSLMode: "prefer"},
+			want: `tls: unsupported sslmode "prefer"`,
+		},
+		{
+			name: "missing file",
+			auth: TLSAuth{SSLCert: filepath.Join(dir, "missing.crt"), SSLKey: keyPath},
+			want: "tls: reading sslcert",
+		},
+		{
+			name: "mismatched key",
+			auth: TLSAuth{SSLCert: otherCertPath, SSLKey: keyPath},
+			want: "do not form a valid key pair",
+		},
+		{
+			name: "bad root certificate",
+			auth: TLSAuth{SSLCert: certPath, SSLKey: keyPath, SSLRootCert: keyPath},
+			want: "contains no PEM encoded certificates",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.auth.validate()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("validate() error = %v, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeAuthConfigUnknownType(t *testing.T) {
+	_, err := DecodeAuthConfig(strings.NewReader(`
+auth_modules:
+  module:
+    type: kerberos
+`))
+	if want := `auth module "module": unknown type "kerberos"`; err == nil || err.Error() != want {
+		t.Fatalf("DecodeAuthConfig() error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+type awsCredentialsKey struct {
+	profile string
+	region  string
+}
+
+var (
+	awsCredentialsMu        sync.Mutex
+	awsCredentialsProviders = map[awsCredentialsKey]aws.CredentialsProvider{}
+)
+
+// awsCredentialsProvider returns the default credential chain of the AWS SDK
+// for profile and region. The providers are kept for the life of the process,
+// so the temporary credentials they fetch, from STS for example, are cached
+// and refreshed by the SDK, and concurrent lookups share a single request.
+// An explicitly configured profile takes precedence over the credentials in
+// the environment of the process.
+func awsCredentialsProvider(ctx context.Context, profile, region string) (aws.CredentialsProvider, error) {
+	key := awsCredentialsKey{profile: profile, region: region}
+	awsCredentialsMu.Lock()
+	provider, ok := awsCredentialsProviders[key]
+	awsCredentialsMu.Unlock()
+	if ok {
+		return provider, nil
+	}
+
+	// Loading the configuration only reads the shared config files, the
+	// credentials are fetched on first use.
+	cfg, err := awsconfig.LoadDefaultConfig(ctx,
+		awsconfig.WithRegion(region),
+		awsconfig.WithSharedConfigProfile(profile),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	awsCredentialsMu.Lock()
+	defer awsCredentialsMu.Unlock()
+	if provider, ok := awsCredentialsProviders[key]; ok {
+		return provider, nil
+	}
+	awsCredentialsProviders[key] = cfg.Credentials
+	return cfg.Credentials, nil
+}
+
+// invalidateAWSCredentials makes provider fetch new credentials on its next
+// use.
+func invalidateAWSCredentials(provider aws.CredentialsProvider) {
+	if cache, ok := provider.(*aws.CredentialsCache); ok {
+		cache.Invalidate()
+	}
+}
+
+// forgetAWSCredentialsProviders drops every provider, so configuration and
+// credential files are read again.
+func forgetAWSCredentialsProviders() {
+	awsCredentialsMu.Lock()
+	defer awsCredentialsMu.Unlock()
+	clear(awsCredentialsProviders)
+}
@@ -20,6 +20,7 @@ import (
 	"log/slog"
 	"maps"
 	"os"
+	"path"
 	"slices"
 	"sync"
 	"time"
@@ -35,19 +36,91 @@ const (
 	DefaultPGStatStatementsIncludeQuery bool = false
 	DefaultPGStatStatementsQueryLength  uint = 120
 	DefaultPGStatStatementsLimit        uint = 100
+
+	DefaultPGStatUserFunctionsLimit uint = 100
+
+	DefaultPreparedXactsIncludeGID bool = false
+	DefaultPreparedXactsGIDLimit   uint = 10
+
+	DefaultSequencesThreshold float64 = 0.5
+	DefaultSequencesLimit     uint    = 10
+
+	DefaultSettingsAudit bool = false
+	DefaultSettingsHash  bool = false
+
+	DefaultTableWraparoundLimit uint = 10
+
+	DefaultIndexHealthIncludeIndexes bool = false
+	DefaultIndexHealthIndexLimit     uint = 10
+
+	DefaultBloatMode     string        = BloatModeEstimate
+	DefaultBloatLimit    uint          = 10
+	DefaultBloatCacheTTL time.Duration = 0
+
+	DefaultTablespacePerDatabase bool = false
+)
+
+var (
+	// DefaultSettingsInfo are the string and enum settings that are most
+	// often audited across clusters. Commands such as archive_command are
+	// left out, as they often hold credentials.
+	DefaultSettingsInfo = []string{
+		"archive_mode",
+		"default_transaction_isolation",
+		"huge_pages",
+		"log_statement",
+		"password_encryption",
+		"shared_preload_libraries",
+		"ssl_min_protocol_version",
+		"synchronous_commit",
+		"synchronous_standby_names",
+		"wal_level",
+	}
+	DefaultSettingsRedact = []string{
+		"*conninfo*",
+		"*password*",
+		"*secret*",
+		"*token*",
+	}
+	// DefaultSettingsHashExclude are settings that legitimately differ
+	// between a primary and its standbys, or between the hosts of a cluster.
+	DefaultSettingsHashExclude = []string{
+		"cluster_name",
+		"config_file",
+		"data_directory",
+		"hba_file",
+		"ident_file",
+		"in_hot_standby",
+		"listen_addresses",
+		"port",
+		"primary_conninfo",
+		"primary_slot_name",
+		"recovery_*",
+		"restore_command",
+		"transaction_*",
+	}
 )
 
 const (
+	CollectorAutovacuumBacklog       = "autovacuum_backlog"
+	CollectorBloat                   = "bloat"
 	CollectorBuffercacheSummary      = "buffercache_summary"
+	CollectorConfigFiles             = "config_files"
+	CollectorConnectionEncryption    = "connection_encryption"
+	CollectorControlFile             = "control_file"
 	CollectorDatabase                = "database"
 	CollectorDatabaseWraparound      = "database_wraparound"
+	CollectorIndexHealth             = "index_health"
 	CollectorLocks                   = "locks"
 	CollectorLongRunningTransactions = "long_running_transactions"
 	CollectorPostmaster              = "postmaster"
+	CollectorPreparedXacts           = "prepared_xacts"
 	CollectorProcessIdle             = "process_idle"
 	CollectorReplication             = "replication"
 	CollectorReplicationSlots        = "replication_slots"
 	CollectorRoles                   = "roles"
+	CollectorSequences               = "sequences"
+	CollectorServerCertificate       = "server_certificate"
 	CollectorSettings                = "settings"
 	CollectorStatActivity            = "stat_activity"
 	CollectorStatActivityAutovacuum  = "stat_activity_autovacuum"
@@ -55,19 +128,42 @@ const (
 	CollectorStatBGWriter            = "stat_bgwriter"
 	CollectorStatCheckpointer        = "stat_checkpointer"
 	CollectorStatDatabase            = "stat_database"
+	CollectorStatIO                  = "stat_io"
+	CollectorStatProgressAnalyze     = "stat_progress_analyze"
+	CollectorStatProgressBasebackup  = "stat_progress_basebackup"
+	CollectorStatProgressCluster     = "stat_progress_cluster"
+	CollectorStatProgressCopy        = "stat_progress_copy"
+	CollectorStatProgressCreateIndex = "stat_progress_create_index"
 	CollectorStatProgressVacuum      = "stat_progress_vacuum"
 	CollectorStatReplication         = "stat_replication"
+	CollectorStatReplicationSlots    = "stat_replication_slots"
 	CollectorStatStatements          = "stat_statements"
+	CollectorStatSubscription        = "stat_subscription"
+	CollectorStatUserFunctions       = "stat_user_functions"
 	CollectorStatUserTables          = "stat_user_tables"
+	CollectorStatWAL                 = "stat_wal"
 	CollectorStatWalReceiver         = "stat_wal_receiver"
 	CollectorStatioUserIndexes       = "statio_user_indexes"
 	CollectorStatioUserTables        = "statio_user_tables"
+	CollectorTableWraparound         = "table_wraparound"
+	CollectorTablespace              = "tablespace"
+	CollectorTempFiles               = "temp_files"
 	CollectorWal                     = "wal"
+	CollectorWalArchive              = "wal_archive"
 	CollectorXlogLocation            = "xlog_location"
 )
 
+// Modes of the bloat collector.
+const (
+	// BloatModeEstimate estimates bloat from the planner statistics.
+	BloatModeEstimate = "estimate"
+	// BloatModeExact measures bloat with the pgstattuple extension.
+	BloatModeExact = "exact"
+)
+
 type Config struct {
 	DataSourceNames       []string
+	CredentialFiles       CredentialFiles
 	MetricPrefix          string
 	CollectionTimeout     time.Duration
 	DisableDefaultMetrics bool
@@ -78,6 +174,14 @@ type Config struct {
 	IncludeDatabases      []string
 	Collectors            map[string]bool
 	PGStatStatements      PGStatStatementsConfig
+	PGStatUserFunctions   PGStatUserFunctionsConfig
+	PreparedXacts         PreparedXactsConfig
+	Sequences             SequencesConfig
+	Settings              SettingsConfig
+	Bloat                 BloatConfig
+	IndexHealth           IndexHealthConfig
+	TableWraparound       TableWraparoundConfig
+	Tablespace            TablespaceConfig
 }
 
 // ValidatedConfig is the result of a successful Config.Validate call. It holds
@@ -111,6 +215,75 @@ type PGStatStatementsConfig struct {
 	ExcludeUsers     []string
 }
 
+// PGStatUserFunctionsConfig configures the stat_user_functions collector.
+// Empty include lists match every schema or function.
+type PGStatUserFunctionsConfig struct {
+	IncludeSchemas   []string
+	ExcludeSchemas   []string
+	IncludeFunctions []string
+	ExcludeFunctions []string
+	Limit            uint
+}
+
+// PreparedXactsConfig configures the prepared_xacts collector. With
+// IncludeGID, the GIDLimit oldest prepared transactions are reported with
+// their gid.
+type PreparedXactsConfig struct {
+	IncludeGID bool
+	GIDLimit   uint
+}
+
+// SequencesConfig configures the sequences collector. Up to Limit of the
+// sequences that have used at least Threshold of their range are reported
+// individually, closest to exhaustion first.
+type SequencesConfig struct {
+	Threshold float64
+	Limit     uint
+}
+
+// SettingsConfig configures the settings collector. With Audit, the string
+// and enum settings matching Info are reported as pg_settings_info, with the
+// value replaced for the settings matching Redact, and with Hash a hash of all
+// settings except the ones matching HashExclude is reported. All lists are
+// shell patterns, as in path.Match.
+type SettingsConfig struct {
+	Audit       bool
+	Info        []string
+	Redact      []string
+	Hash        bool
+	HashExclude []string
+}
+
+// BloatConfig configures the bloat collector, which reports the Limit tables
+// and btree indexes with the most bloat, measured as in Mode. With a positive
+// CacheTTL, the results are reused for that long instead of being measured on
+// every scrape.
+type BloatConfig struct {
+	Mode     string
+	Limit    uint
+	CacheTTL time.Duration
+}
+
+// IndexHealthConfig configures the index_health collector. With
+// IncludeIndexes, up to IndexLimit of the largest indexes and foreign keys
+// with each problem are reported individually.
+type IndexHealthConfig struct {
+	IncludeIndexes bool
+	IndexLimit     uint
+}
+
+// TableWraparoundConfig configures the table_wraparound collector, which
+// reports the Limit relations closest to a forced anti-wraparound vacuum.
+type TableWraparoundConfig struct {
+	Limit uint
+}
+
+// TablespaceConfig configures the tablespace collector. With PerDatabase, the
+// size of the connected database is also broken down per tablespace.
+type TablespaceConfig struct {
+	PerDatabase bool
+}
+
 func NewConfigWithDefaults() Config {
 	return Config{
 		MetricPrefix:      DefaultMetricPrefix,
@@ -121,6 +294,39 @@ func NewConfigWithDefaults() Config {
 			QueryLength:  DefaultPGStatStatementsQueryLength,
 			Limit:        DefaultPGStatStatementsLimit,
 		},
+		PGStatUserFunctions: PGStatUserFunctionsConfig{
+			Limit: DefaultPGStatUserFunctionsLimit,
+		},
+		PreparedXacts: PreparedXactsConfig{
+			IncludeGID: DefaultPreparedXactsIncludeGID,
+			GIDLimit:   DefaultPreparedXactsGIDLimit,
+		},
+		Sequences: SequencesConfig{
+			Threshold: DefaultSequencesThreshold,
+			Limit:     DefaultSequencesLimit,
+		},
+		Settings: SettingsConfig{
+			Audit:       DefaultSettingsAudit,
+			Info:        slices.Clone(DefaultSettingsInfo),
+			Redact:      slices.Clone(DefaultSettingsRedact),
+			Hash:        DefaultSettingsHash,
+			HashExclude: slices.Clone(DefaultSettingsHashExclude),
+		},
+		Bloat: BloatConfig{
+			Mode:     DefaultBloatMode,
+			Limit:    DefaultBloatLimit,
+			CacheTTL: DefaultBloatCacheTTL,
+		},
+		IndexHealth: IndexHealthConfig{
+			IncludeIndexes: DefaultIndexHealthIncludeIndexes,
+			IndexLimit:     DefaultIndexHealthIndexLimit,
+		},
+		TableWraparound: TableWraparoundConfig{
+			Limit: DefaultTableWraparoundLimit,
+		},
+		Tablespace: TablespaceConfig{
+			PerDatabase: DefaultTablespacePerDatabase,
+		},
 	}
 }
 
@@ -141,12 +347,49 @@ func (c Config) Validate() (ValidatedConfig, error) {
 			return ValidatedConfig{}, fmt.Errorf("data source name at index %d must not be empty", i)
 		}
 	}
+	if err := c.CredentialFiles.validate(); err != nil {
+		return ValidatedConfig{}, err
+	}
 	if c.PGStatStatements.QueryLength <= 0 {
 		return ValidatedConfig{}, fmt.Errorf("pg_stat_statements query length must be greater than zero")
 	}
 	if c.PGStatStatements.Limit <= 0 {
 		return ValidatedConfig{}, fmt.Errorf("pg_stat_statements limit must be greater than zero")
 	}
+	if c.PGStatUserFunctions.Limit <= 0 {
+		return ValidatedConfig{}, fmt.Errorf("pg_stat_user_functions limit must be greater than zero")
+	}
+	if c.PreparedXacts.GIDLimit <= 0 {
+		return ValidatedConfig{}, fmt.Errorf("prepared_xacts gid limit must be greater than zero")
+	}
+	if c.Bloat.Mode != BloatModeEstimate && c.Bloat.Mode != BloatModeExact {
+		return ValidatedConfig{}, fmt.Errorf("bloat mode %q is invalid, must be %q or %q", c.Bloat.Mode, BloatModeEstimate, BloatModeExact)
+	}
+	if c.Bloat.Limit <= 0 {
+		return ValidatedConfig{}, fmt.Errorf("bloat limit must be greater than zero")
+	}
+	if c.Bloat.CacheTTL < 0 {
+		return ValidatedConfig{}, fmt.Errorf("bloat cache ttl must not be negative")
+	}
+	if c.IndexHealth.IndexLimit <= 0 {
+		return ValidatedConfig{}, fmt.Errorf("index_health index limit must be greater than zero")
+	}
+	if c.TableWraparound.Limit <= 0 {
+		return ValidatedConfig{}, fmt.Errorf("table_wraparound limit must be greater than zero")
+	}
+	if c.Sequences.Threshold < 0 || c.Sequences.Threshold > 1 {
+		return ValidatedConfig{}, fmt.Errorf("sequences threshold must be between 0 and 1")
+	}
+	if c.Sequences.Limit <= 0 {
+		return ValidatedConfig{}, fmt.Errorf("sequences limit must be greater than zero")
+	}
+	for _, patterns := range [][]string{c.Settings.Info, c.Settings.Redact, c.Settings.HashExclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return ValidatedConfig{}, fmt.Errorf("settings pattern %q is invalid", pattern)
+			}
+		}
+	}
 	for name := range c.Collectors {
 		if name == "" {
 			return ValidatedConfig{}, fmt.Errorf("collector name must not be empty")
@@ -169,21 +412,37 @@ func (c Config) clone() Config {
 	c.Collectors = maps.Clone(c.Collectors)
 	c.PGStatStatements.ExcludeDatabases = slices.Clone(c.PGStatStatements.ExcludeDatabases)
 	c.PGStatStatements.ExcludeUsers = slices.Clone(c.PGStatStatements.ExcludeUsers)
+	c.PGStatUserFunctions.IncludeSchemas = slices.Clone(c.PGStatUserFunctions.IncludeSchemas)
+	c.PGStatUserFunctions.ExcludeSchemas = slices.Clone(c.PGStatUserFunctions.ExcludeSchemas)
+	c.PGStatUserFunctions.IncludeFunctions = slices.Clone(c.PGStatUserFunctions.IncludeFunctions)
+	c.PGStatUserFunctions.ExcludeFunctions = slices.Clone(c.PGStatUserFunctions.ExcludeFunctions)
+	c.Settings.Info = slices.Clone(c.Settings.Info)
+	c.Settings.Redact = slices.Clone(c.Settings.Redact)
+	c.Settings.HashExclude = slices.Clone(c.Settings.HashExclude)
 	return c
 }
 
 func DefaultCollectorConfig() map[string]bool {
 	return map[string]bool{
+		CollectorAutovacuumBacklog:       false,
+		CollectorBloat:                   false,
 		CollectorBuffercacheSummary:      false,
+		CollectorConfigFiles:             false,
+		CollectorConnectionEncryption:    false,
+		CollectorControlFile:             false,
 		CollectorDatabase:                true,
 		CollectorDatabaseWraparound:      false,
+		CollectorIndexHealth:             false,
 		CollectorLocks:                   true,
 		CollectorLongRunningTransactions: false,
 		CollectorPostmaster:              false,
+		CollectorPreparedXacts:           false,
 		CollectorProcessIdle:             false,
 		CollectorReplication:             true,
 		CollectorReplicationSlots:        true,
 		CollectorRoles:                   true,
+		CollectorSequences:               false,
+		CollectorServerCertificate:       false,
 		CollectorSettings:                true,
 		CollectorStatActivity:            true,
 		CollectorStatActivityAutovacuum:  false,
@@ -191,14 +450,28 @@ func DefaultCollectorConfig() map[string]bool {
 		CollectorStatBGWriter:            true,
 		CollectorStatCheckpointer:        false,
 		CollectorStatDatabase:            true,
+		CollectorStatIO:                  false,
+		CollectorStatProgressAnalyze:     false,
+		CollectorStatProgressBasebackup:  false,
+		CollectorStatProgressCluster:     false,
+		CollectorStatProgressCopy:        false,
+		CollectorStatProgressCreateIndex: false,
 		CollectorStatProgressVacuum:      true,
 		CollectorStatReplication:         true,
+		CollectorStatReplicationSlots:    false,
 		CollectorStatStatements:          false,
+		CollectorStatSubscription:        false,
+		CollectorStatUserFunctions:       false,
 		CollectorStatUserTables:          true,
+		CollectorStatWAL:                 false,
 		CollectorStatWalReceiver:         false,
 		CollectorStatioUserIndexes:       false,
 		CollectorStatioUserTables:        true,
+		CollectorTableWraparound:         false,
+		CollectorTablespace:              false,
+		CollectorTempFiles:               false,
 		CollectorWal:                     true,
+		CollectorWalArchive:              false,
 		CollectorXlogLocation:            false,
 	}
 }
@@ -207,16 +480,51 @@ type AuthConfig struct {
 	AuthModules map[string]AuthModule `yaml:"auth_modules"`
 }
 
+const (
+	AuthModuleTypeUserPass = "userpass"
+	AuthModuleTypeTLS      = "tls"
+	AuthModuleTypeAWSIAM   = "aws_iam"
+	AuthModuleTypeExec     = "exec"
+)
+
 type AuthModule struct {
-	Type     string   `yaml:"type"`
-	UserPass UserPass `yaml:"userpass,omitempty"`
+	Type     string     `yaml:"type"`
+	UserPass UserPass   `yaml:"userpass,omitempty"`
+	TLS      TLSAuth    `yaml:"tls,omitempty"`
+	AWSIAM   AWSIAMAuth `yaml:"aws_iam,omitempty"`
+	Exec     ExecAuth   `yaml:"exec,omitempty"`
 	// Add alternative auth modules here
 	Options map[string]string `yaml:"options"`
+
+	// name is the key of the module in auth_modules.
+	name string
 }
 
+// UserPass holds static credentials. The username and password can be read
+// from files instead, which are re-read when they change.
 type UserPass struct {
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	UsernameFile string `yaml:"username_file"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// CredentialFiles returns the files the credentials are read from.
+func (u UserPass) CredentialFiles() CredentialFiles {
+	return CredentialFiles{UsernameFile: u.UsernameFile, PasswordFile: u.PasswordFile}
+}
+
+func (u UserPass) validate() error {
+	if u.Username != "" && u.UsernameFile != "" {
+		return errors.New("userpass: only one of username and username_file may be set")
+	}
+	if u.Password != "" && u.PasswordFile != "" {
+		return errors.New("userpass: only one of password and password_file may be set")
+	}
+	if err := u.CredentialFiles().validate(); err != nil {
+		return fmt.Errorf("userpass: %w", err)
+	}
+	return nil
 }
 
 type Handler struct {
@@ -244,7 +552,7 @@ func NewHandler(registerer prometheus.Registerer) (*Handler, error) {
 			Help:      "Timestamp of the last successful configuration reload.",
 		}),
 	}
-	registerer.MustRegister(h.configReloadSuccess, h.configReloadSeconds)
+	registerer.MustRegister(h.configReloadSuccess, h.configReloadSeconds, execRunsTotal, execFailuresTotal)
 
 	return h, nil
 }
@@ -306,13 +614,49 @@ func DecodeAuthConfig(r io.Reader) (*AuthConfig, error) {
 	if err := decoder.Decode(config); err != nil {
 		return nil, err
 	}
+	for name, m := range config.AuthModules {
+		m.name = name
+		config.AuthModules[name] = m
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
+// Validate checks every auth module for settings that would only fail later,
+// when a probe uses the module.
+func (c *AuthConfig) Validate() error {
+	for _, name := range slices.Sorted(maps.Keys(c.AuthModules)) {
+		if err := c.AuthModules[name].validate(); err != nil {
+			return fmt.Errorf("auth module %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (m AuthModule) validate() error {
+	switch m.Type {
+	case AuthModuleTypeUserPass:
+		return m.UserPass.validate()
+	case AuthModuleTypeTLS:
+		return m.TLS.validate()
+	case AuthModuleTypeAWSIAM:
+		return m.AWSIAM.validate(m.Options)
+	case AuthModuleTypeExec:
+		return m.Exec.validate()
+	default:
+		return fmt.Errorf("unknown type %q", m.Type)
+	}
+}
+
+// SetAuthConfig replaces the auth configuration. Credentials cached for the
+// previous configuration are dropped, as the modules may have changed.
 func (ch *Handler) SetAuthConfig(config *AuthConfig) {
 	ch.Lock()
 	ch.Config = config
 	ch.Unlock()
+	resetCredentialCaches()
 }
 
 func (m AuthModule) ConfigureTarget(target string) (DSN, error) {
@@ -323,13 +667,29 @@ func (m AuthModule) ConfigureTarget(target string) (DSN, error) {
 
 	// Set the credentials from the authentication module
 	// TODO(@sysadmind): What should the order of precedence be?
-	if m.Type == "userpass" {
+	switch m.Type {
+	case AuthModuleTypeUserPass:
 		if m.UserPass.Username != "" {
 			dsn.username = m.UserPass.Username
 		}
 		if m.UserPass.Password != "" {
 			dsn.password = m.UserPass.Password
 		}
+		if _, err := m.UserPass.CredentialFiles().apply(&dsn, false); err != nil {
+			return DSN{}, err
+		}
+	case AuthModuleTypeTLS:
+		if err := m.TLS.configure(&dsn); err != nil {
+			return DSN{}, err
+		}
+	case AuthModuleTypeAWSIAM:
+		if err := m.AWSIAM.configure(&dsn); err != nil {
+			return DSN{}, err
+		}
+	case AuthModuleTypeExec:
+		if err := m.Exec.configure(&dsn, m.name, target); err != nil {
+			return DSN{}, err
+		}
 	}
 
 	for k, v := range m.Options {
@@ -154,6 +154,76 @@ func TestConfigValidateFailures(t *testing.T) {
 			},
 			want: "pg_stat_statements limit must be greater than zero",
 		},
+		{
+			name: "zero pg_stat_user_functions limit",
+			mutate: func(cfg *Config) {
+				cfg.PGStatUserFunctions.Limit = 0
+			},
+			want: "pg_stat_user_functions limit must be greater than zero",
+		},
+		{
+			name: "zero prepared_xacts gid limit",
+			mutate: func(cfg *Config) {
+				cfg.PreparedXacts.GIDLimit = 0
+			},
+			want: "prepared_xacts gid limit must be greater than zero",
+		},
+		{
+			name: "invalid bloat mode",
+			mutate: func(cfg *Config) {
+				cfg.Bloat.Mode = "pgstattuple"
+			},
+			want: `bloat mode "pgstattuple" is invalid, must be "estimate" or "exact"`,
+		},
+		{
+			name: "zero bloat limit",
+			mutate: func(cfg *Config) {
+				cfg.Bloat.Limit = 0
+			},
+			want: "bloat limit must be greater than zero",
+		},
+		{
+			name: "negative bloat cache ttl",
+			mutate: func(cfg *Config) {
+				cfg.Bloat.CacheTTL = -time.Second
+			},
+			want: "bloat cache ttl must not be negative",
+		},
+		{
+			name: "zero index_health index limit",
+			mutate: func(cfg *Config) {
+				cfg.IndexHealth.IndexLimit = 0
+			},
+			want: "index_health index limit must be greater than zero",
+		},
+		{
+			name: "zero table_wraparound limit",
+			mutate: func(cfg *Config) {
+				cfg.TableWraparound.Limit = 0
+			},
+			want: "table_wraparound limit must be greater than zero",
+		},
+		{
+			name: "sequences threshold above 1",
+			mutate: func(cfg *Config) {
+				cfg.Sequences.Threshold = 1.5
+			},
+			want: "sequences threshold must be between 0 and 1",
+		},
+		{
+			name: "zero sequences limit",
+			mutate: func(cfg *Config) {
+				cfg.Sequences.Limit = 0
+			},
+			want: "sequences limit must be greater than zero",
+		},
+		{
+			name: "invalid settings pattern",
+			mutate: func(cfg *Config) {
+				cfg.Settings.Redact = append(cfg.Settings.Redact, "[password")
+			},
+			want: `settings pattern "[password" is invalid`,
+		},
 		{
 			name: "empty collector name",
 			mutate: func(cfg *Config) {
@@ -0,0 +1,230 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CredentialFiles names files that hold the username and password for a data
+// source, such as mounted Kubernetes secrets. The files are checked for
+// changes every time a connection is opened, so rotated secrets take effect
+// without a restart.
+type CredentialFiles struct {
+	UsernameFile string
+	PasswordFile string
+}
+
+// IsZero reports whether no credential files are set.
+func (f CredentialFiles) IsZero() bool {
+	return f.UsernameFile == "" && f.PasswordFile == ""
+}
+
+// validate only checks the paths. The files are read when a connection is
+// opened, so a secret that is not mounted yet is not a configuration error.
+func (f CredentialFiles) validate() error {
+	for _, path := range []string{f.UsernameFile, f.PasswordFile} {
+		if path == "" {
+			continue
+		}
+		if strings.TrimSpace(path) != path || strings.ContainsRune(path, 0) {
+			return fmt.Errorf("credentials file %q is not a valid path", path)
+		}
+	}
+	return nil
+}
+
+// apply sets the username and password from the files on the dsn. With force,
+// the files are read even if they look unchanged. It reports whether any
+// value differs from the one in the dsn.
+func (f CredentialFiles) apply(dsn *DSN, force bool) (bool, error) {
+	changed := false
+	if f.UsernameFile != "" {
+		username, err := readSecretFile(f.UsernameFile, force)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || username != dsn.username
+		dsn.username = username
+	}
+	if f.PasswordFile != "" {
+		password, err := readSecretFile(f.PasswordFile, force)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || password != dsn.password
+		dsn.password = password
+	}
+	return changed, nil
+}
+
+type secretFile struct {
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+var (
+	secretFilesMu sync.Mutex
+	secretFiles   = map[string]secretFile{}
+)
+
+// readSecretFile returns the trimmed contents of path. The contents are cached
+// and only read again when the modification time or size of the file changes,
+// or when force is set.
+func readSecretFile(path string, force bool) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("reading credentials file: %w", err)
+	}
+
+	secretFilesMu.Lock()
+	defer secretFilesMu.Unlock()
+
+	cached, ok := secretFiles[path]
+	if ok && !force && cached.modTime.Equal(fi.ModTime()) && cached.size == fi.Size() {
+		return cached.value, nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading credentials file: %w", err)
+	}
+	value := strings.TrimSpace(string(contents))
+	secretFiles[path] = secretFile{modTime: fi.ModTime(), size: fi.Size(), value: value}
+	return value, nil
+}
+
+// OpenDB returns a database handle for dsn. Without credential files it is
+// the same as sql.Open("postgres", dsn), except that credentials the server
+// rejects are dropped from the caches of the auth modules. With them, every
+// new connection takes its username and password from the files, and a
+// connection refused for invalid credentials is retried once if re-reading
+// the files turns up different credentials.
+func OpenDB(dsn string, files CredentialFiles) (*sql.DB, error) {
+	if files.IsZero() {
+		return sql.OpenDB(&dsnConnector{dsn: dsn}), nil
+	}
+	parsed, err := dsnFromString(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&credentialFilesConnector{dsn: parsed, files: files}), nil
+}
+
+type dsnConnector struct {
+	dsn     string
+	observe func(TLSHandshake)
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := connect(ctx, c.dsn, c.observe)
+	if isAuthenticationFailure(err) {
+		forgetRejectedCredentials(c.dsn)
+	}
+	return conn, err
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+type credentialFilesConnector struct {
+	dsn     DSN
+	files   CredentialFiles
+	observe func(TLSHandshake)
+}
+
+func (c *credentialFilesConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn := c.dsn
+	if _, err := c.files.apply(&dsn, false); err != nil {
+		return nil, err
+	}
+	conn, err := connect(ctx, dsn.GetConnectionString(), c.observe)
+	if !isAuthenticationFailure(err) {
+		return conn, err
+	}
+
+	changed, rerr := c.files.apply(&dsn, true)
+	if rerr != nil || !changed {
+		return nil, err
+	}
+	return connect(ctx, dsn.GetConnectionString(), c.observe)
+}
+
+func (c *credentialFilesConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// connect opens a connection to dsn with the driver, observing its TLS
+// handshakes if observe is set.
+func connect(ctx context.Context, dsn string, observe func(TLSHandshake)) (driver.Conn, error) {
+	cfg, err := DriverConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if observe != nil {
+		return connectObservingTLS(ctx, cfg, observe)
+	}
+	return connectConfig(ctx, cfg)
+}
+
+func connectConfig(ctx context.Context, cfg pq.Config) (driver.Conn, error) {
+	connector, err := pq.NewConnectorConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+// isAuthenticationFailure reports whether err is the server rejecting the
+// credentials, as opposed to a network or configuration problem.
+func isAuthenticationFailure(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "28000", "28P01":
+		return true
+	}
+	return false
+}
+
+// forgetRejectedCredentials drops the password of dsn from the caches of the
+// auth modules after the server rejected it, so the next connection fetches
+// new credentials instead of failing until the cached ones expire.
+func forgetRejectedCredentials(dsn string) {
+	parsed, err := dsnFromString(dsn)
+	if err != nil || parsed.password == "" {
+		return
+	}
+	forgetRDSAuthToken(parsed.password)
+	forgetExecCredentials(parsed.password)
+}
+
+// resetCredentialCaches drops every credential cached by the auth modules.
+func resetCredentialCaches() {
+	forgetRDSAuthToken("")
+	forgetExecCredentials("")
+}
@@ -0,0 +1,248 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// writeSecret writes value to path and pins its modification time, so tests
+// can rewrite a file without the change being visible through stat.
+func writeSecret(t *testing.T, path, value string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(value+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeSecret(t, path, "old", modTime)
+
+	if got, err := readSecretFile(path, false); err != nil || got != "old" {
+		t.Fatalf("readSecretFile() = %q, %v, want old", got, err)
+	}
+
+	// Same size and modification time, the cached value is used.
+	writeSecret(t, path, "new", modTime)
+	if got, _ := readSecretFile(path, false); got != "old" {
+		t.Fatalf("readSecretFile() = %q, want the cached value", got)
+	}
+	if got, _ := readSecretFile(path, true); got != "new" {
+		t.Fatalf("readSecretFile(force) = %q, want new", got)
+	}
+
+	writeSecret(t, path, "rotated", modTime.Add(time.Minute))
+	if got, _ := readSecretFile(path, false); got != "rotated" {
+		t.Fatalf("readSecretFile() = %q, want the rotated value", got)
+	}
+}
+
+func TestUserPassFiles(t *testing.T) {
+	dir := t.TempDir()
+	usernameFile := filepath.Join(dir, "username")
+	passwordFile := filepath.Join(dir, "password")
+	writeSecret(t, usernameFile, "file-user", time.Now())
+	writeSecret(t, passwordFile, "file-pass", time.Now())
+
+	module := AuthModule{
+		Type:     AuthModuleTypeUserPass,
+		UserPass: UserPass{UsernameFile: usernameFile, PasswordFile: passwordFile},
+	}
+	if err := module.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	dsn, err := module.ConfigureTarget("db.example.com:5432")
+	if err != nil {
+		t.Fatalf("ConfigureTarget() error = %v", err)
+	}
+	if dsn.username != "file-user" || dsn.password != "file-pass" {
+		t.Fatalf("credentials = %q/%q, want file-user/file-pass", dsn.username, dsn.password)
+	}
+
+	tests := []struct {
+		name     string
+		userPass UserPass
+		want     string
+	}{
+		{
+			name:     "username and username_file",
+			userPass: UserPass{Username: "user", UsernameFile: usernameFile},
+			want:     "userpass: only one of username and username_file may be set",
+		},
+		{
+			name:     "password and password_file",
+			userPass: UserPass{Password: "pass", PasswordFile: passwordFile},
+			want:     "userpass: only one of password and password_file may be set",
+		},
+		{
+			name:     "invalid password_file",
+			userPass: UserPass{Username: "user", PasswordFile: filepath.Join(dir, "password ")},
+			want:     "userpass: credentials file",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.userPass.validate()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("validate() error = %v, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
+
+// fakeCleartextServer accepts connections speaking just enough of the
+// PostgreSQL protocol to check a cleartext password. It returns the address
+// and a function reporting the passwords that were tried.
+func fakeCleartextServer(t *testing.T, password string) (string, func() []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	var tried []string
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+
+				// Startup message: length, then payload.
+				var length int32
+				if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+					return
+				}
+				if _, err := io.CopyN(io.Discard, r, int64(length-4)); err != nil {
+					return
+				}
+				conn.Write([]byte{'R', 0, 0, 0, 8, 0, 0, 0, 3})
+
+				// Password message.
+				if _, err := r.ReadByte(); err != nil {
+					return
+				}
+				if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+					return
+				}
+				body := make([]byte, length-4)
+				if _, err := io.ReadFull(r, body); err != nil {
+					return
+				}
+				got := strings.TrimRight(string(body), "\x00")
+				mu.Lock()
+				tried = append(tried, got)
+				mu.Unlock()
+
+				if got != password {
+					fields := "SFATAL\x00VFATAL\x00C28P01\x00Mpassword authentication failed\x00\x00"
+					msg := []byte{'E', 0, 0, 0, 0}
+					binary.BigEndian.PutUint32(msg[1:], uint32(4+len(fields)))
+					conn.Write(append(msg, fields...))
+					return
+				}
+				conn.Write([]byte{'R', 0, 0, 0, 8, 0, 0, 0, 0})
+				conn.Write([]byte{'Z', 0, 0, 0, 5, 'I'})
+				io.Copy(io.Discard, r)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), tried...)
+	}
+}
+
+func TestCredentialFilesReadOnConnect(t *testing.T) {
+	files := CredentialFiles{PasswordFile: filepath.Join(t.TempDir(), "missing")}
+	// A secret that is not mounted yet only fails the connection.
+	if err := files.validate(); err != nil {
+		t.Fatalf("validate() error = %v, want a missing file to be accepted", err)
+	}
+
+	db, err := OpenDB("postgresql://exporter@127.0.0.1:1/postgres?sslmode=disable", files)
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err == nil || !strings.Contains(err.Error(), "reading credentials file") {
+		t.Fatalf("Ping() error = %v, want the file read error", err)
+	}
+}
+
+func TestOpenDBRereadsPasswordOnAuthenticationFailure(t *testing.T) {
+	addr, tried := fakeCleartextServer(t, "new")
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeSecret(t, passwordFile, "old", modTime)
+	if _, err := readSecretFile(passwordFile, false); err != nil {
+		t.Fatal(err)
+	}
+	// The rotation is not visible through stat, only a forced re-read sees it.
+	writeSecret(t, passwordFile, "new", modTime)
+
+	db, err := OpenDB("postgresql://exporter@"+addr+"/postgres?sslmode=disable", CredentialFiles{PasswordFile: passwordFile})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn() error = %v", err)
+	}
+	conn.Close()
+
+	if got, want := strings.Join(tried(), ","), "old,new"; got != want {
+		t.Fatalf("passwords tried = %s, want %s", got, want)
+	}
+}
+
+func TestIsAuthenticationFailure(t *testing.T) {
+	if !isAuthenticationFailure(&pq.Error{Code: "28P01"}) {
+		t.Fatal("isAuthenticationFailure(28P01) = false, want true")
+	}
+	if isAuthenticationFailure(&pq.Error{Code: "57P03"}) {
+		t.Fatal("isAuthenticationFailure(57P03) = true, want false")
+	}
+	if isAuthenticationFailure(io.EOF) {
+		t.Fatal("isAuthenticationFailure(EOF) = true, want false")
+	}
+}
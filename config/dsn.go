@@ -15,7 +15,9 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"net/url"
+	"os"
 	"regexp"
 	"strings"
 	"unicode"
@@ -34,15 +36,21 @@ type DSN struct {
 // String makes a dsn safe to print by excluding any passwords. This allows dsn to be used in
 // strings and log messages without needing to call a redaction function first.
 func (d DSN) String() string {
+	query := d.query
+	if query.Get("sslinline") == "true" && query.Has("sslkey") {
+		query = maps.Clone(query)
+		query.Set("sslkey", "******")
+	}
+
 	if d.password != "" {
-		return fmt.Sprintf("%s://%s:******@%s%s?%s", d.scheme, d.username, d.host, d.path, d.query.Encode())
+		return fmt.Sprintf("%s://%s:******@%s%s?%s", d.scheme, d.username, d.host, d.path, query.Encode())
 	}
 
 	if d.username != "" {
-		return fmt.Sprintf("%s://%s@%s%s?%s", d.scheme, d.username, d.host, d.path, d.query.Encode())
+		return fmt.Sprintf("%s://%s@%s%s?%s", d.scheme, d.username, d.host, d.path, query.Encode())
 	}
 
-	return fmt.Sprintf("%s://%s%s?%s", d.scheme, d.host, d.path, d.query.Encode())
+	return fmt.Sprintf("%s://%s%s?%s", d.scheme, d.host, d.path, query.Encode())
 }
 
 // GetConnectionString returns the URL to pass to the driver for database connections. This value should not be logged.
@@ -53,6 +61,11 @@ func (d DSN) GetConnectionString() string {
 		Path:     d.path,
 		RawQuery: d.query.Encode(),
 	}
+	// The driver only takes a URL with an authority, which is empty when the
+	// host comes from a service
+	if u.Host == "" && u.Path == "" {
+		u.Path = "/"
+	}
 
 	// Username and Password
 	if d.username != "" {
@@ -160,7 +173,8 @@ func dsnFromKeyValue(in string) (DSN, error) {
 		}
 	}
 
-	if hostname == "" {
+	// The host of a service is filled in by the driver
+	if hostname == "" && !query.Has("service") && os.Getenv("PGSERVICE") == "" {
 		hostname = "localhost"
 	}
 
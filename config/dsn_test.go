@@ -15,8 +15,12 @@ package config
 
 import (
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/lib/pq"
 )
 
 // Test_dsn_String is designed to test different dsn combinations for their string representation.
@@ -226,3 +230,60 @@ func Test_dsnFromString(t *testing.T) {
 		})
 	}
 }
+
+// Services and password files are resolved by the driver, so the same target
+// connects the same way through /probe and DATA_SOURCE_NAME.
+func TestDSNServiceResolvedByDriver(t *testing.T) {
+	serviceFile := filepath.Join(t.TempDir(), "pg_service.conf")
+	if err := os.WriteFile(serviceFile, []byte(`
+[orders]
+host=orders.example.com
+port=6432
+dbname=orders
+user=monitor
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGSERVICE", "")
+	t.Setenv("PGSERVICEFILE", serviceFile)
+
+	tests := []struct {
+		input    string
+		host     string
+		port     uint16
+		user     string
+		database string
+	}{
+		{input: "service=orders", host: "orders.example.com", port: 6432, user: "monitor", database: "orders"},
+		{input: "postgresql://?service=orders", host: "orders.example.com", port: 6432, user: "monitor", database: "orders"},
+		{input: "postgresql:///?service=orders&sslmode=disable", host: "orders.example.com", port: 6432, user: "monitor", database: "orders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			dsn, err := dsnFromString(tt.input)
+			if err != nil {
+				t.Fatalf("dsnFromString() error = %v", err)
+			}
+			cfg, err := pq.NewConfig(dsn.GetConnectionString())
+			if err != nil {
+				t.Fatalf("pq.NewConfig() error = %v", err)
+			}
+			if cfg.Host != tt.host || cfg.Port != tt.port || cfg.User != tt.user || cfg.Database != tt.database {
+				t.Fatalf("driver config = %s:%d %s/%s, want %s:%d %s/%s",
+					cfg.Host, cfg.Port, cfg.User, cfg.Database, tt.host, tt.port, tt.user, tt.database)
+			}
+		})
+	}
+
+	dsn, err := dsnFromString("host=db.example.com passfile=/etc/postgres_exporter/pgpass")
+	if err != nil {
+		t.Fatalf("dsnFromString() error = %v", err)
+	}
+	cfg, err := pq.NewConfig(dsn.GetConnectionString())
+	if err != nil {
+		t.Fatalf("pq.NewConfig() error = %v", err)
+	}
+	if cfg.Passfile != "/etc/postgres_exporter/pgpass" {
+		t.Fatalf("passfile = %q, want it passed to the driver", cfg.Passfile)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// defaultSysConfDir is used when PGSYSCONFDIR is not set. libpq has it compiled
+// in, this is the default of a source build.
+const defaultSysConfDir = "/usr/local/pgsql/etc"
+
+// DriverConfig parses dsn the way it is connected to: by the driver, with the
+// service looked up like libpq does.
+func DriverConfig(dsn string) (pq.Config, error) {
+	dsn, err := withSystemService(dsn)
+	if err != nil {
+		return pq.Config{}, err
+	}
+	return pq.NewConfig(dsn)
+}
+
+// withSystemService fills in the parameters of the service named by dsn or
+// PGSERVICE from pg_service.conf in PGSYSCONFDIR. The driver only reads the
+// service file of the user, libpq falls back to the system-wide one for
+// services the user's file does not define. dsn is returned unchanged when
+// there is nothing to fill in, leaving errors to the driver.
+func withSystemService(dsn string) (string, error) {
+	d, err := dsnFromString(dsn)
+	if err != nil {
+		return dsn, nil
+	}
+	name := d.query.Get("service")
+	if name == "" {
+		name = os.Getenv("PGSERVICE")
+	}
+	if name == "" {
+		return dsn, nil
+	}
+
+	userFile := os.Getenv("PGSERVICEFILE")
+	if userFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			userFile = filepath.Join(home, ".pg_service.conf")
+		}
+	}
+	if userFile != "" {
+		params, err := readServiceFile(userFile, name)
+		if err != nil || params != nil {
+			return dsn, err
+		}
+	}
+
+	sysConfDir := os.Getenv("PGSYSCONFDIR")
+	if sysConfDir == "" {
+		sysConfDir = defaultSysConfDir
+	}
+	params, err := readServiceFile(filepath.Join(sysConfDir, "pg_service.conf"), name)
+	if err != nil || params == nil {
+		return dsn, err
+	}
+
+	// The parameters of the dsn take precedence over those of the service.
+	// The service is cleared, so the driver does not look it up again.
+	merged := d.keyValues()
+	for k, v := range params {
+		if _, ok := merged[k]; !ok {
+			merged[k] = v
+		}
+	}
+	merged["service"] = ""
+	return joinKeyValues(merged), nil
+}
+
+// readServiceFile returns the parameters of service in the file at path, or
+// nil if the file does not exist or does not define the service.
+func readServiceFile(path, service string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening service file: %w", err)
+	}
+	defer f.Close()
+
+	var params map[string]string
+	inService := false
+	lineNo := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if inService {
+				break
+			}
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("syntax error in service file %q, line %d", path, lineNo)
+			}
+			inService = strings.TrimSpace(line[1:len(line)-1]) == service
+			if inService {
+				params = map[string]string{}
+			}
+			continue
+		}
+		if !inService {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("syntax error in service file %q, line %d", path, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		if key == "service" {
+			return nil, fmt.Errorf("nested service specifications not supported in service file %q, line %d", path, lineNo)
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading service file: %w", err)
+	}
+	return params, nil
+}
+
+// keyValues returns the parameters set by the dsn, named like in a key=value
+// connection string.
+func (d DSN) keyValues() map[string]string {
+	kv := make(map[string]string, len(d.query)+5)
+	for k := range d.query {
+		kv[k] = d.query.Get(k)
+	}
+	if d.username != "" {
+		kv["user"] = d.username
+	}
+	if d.password != "" {
+		kv["password"] = d.password
+	}
+	if host, port, err := net.SplitHostPort(d.host); err == nil {
+		kv["host"] = host
+		if port != "" {
+			kv["port"] = port
+		}
+	} else if d.host != "" {
+		kv["host"] = d.host
+	}
+	if dbname := strings.TrimPrefix(d.path, "/"); dbname != "" {
+		kv["dbname"] = dbname
+	}
+	return kv
+}
+
+// joinKeyValues formats kv as a key=value connection string.
+func joinKeyValues(kv map[string]string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	pairs := make([]string, 0, len(kv))
+	for k, v := range kv {
+		pairs = append(pairs, k+"='"+escaper.Replace(v)+"'")
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, " ")
+}
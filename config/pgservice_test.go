@@ -0,0 +1,116 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeServiceFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDriverConfigService(t *testing.T) {
+	sysConfDir := t.TempDir()
+	writeServiceFile(t, sysConfDir, "pg_service.conf", `
+# shared by every user of the host
+[orders]
+host=orders.example.com
+port=6432
+dbname=orders
+user=monitor
+
+[billing]
+host=billing.example.com
+sslmode=require
+`)
+	userFile := writeServiceFile(t, t.TempDir(), "pg_service.conf", `
+[billing]
+host=billing-replica.example.com
+`)
+	t.Setenv("PGSYSCONFDIR", sysConfDir)
+	t.Setenv("PGSERVICEFILE", userFile)
+	t.Setenv("PGSERVICE", "")
+
+	tests := []struct {
+		name     string
+		dsn      string
+		host     string
+		port     uint16
+		user     string
+		database string
+	}{
+		{
+			name: "system file",
+			dsn:  "service=orders", host: "orders.example.com", port: 6432, user: "monitor", database: "orders",
+		},
+		{
+			name: "system file url",
+			dsn:  "postgresql://?service=orders", host: "orders.example.com", port: 6432, user: "monitor", database: "orders",
+		},
+		{
+			name: "dsn overrides service",
+			dsn:  "postgresql://exporter@orders-replica.example.com/?service=orders", host: "orders-replica.example.com", port: 6432, user: "exporter", database: "orders",
+		},
+		{
+			// Services of the user's file are not merged with those of
+			// the system-wide file.
+			name: "user file",
+			dsn:  "service=billing user=monitor dbname=billing", host: "billing-replica.example.com", port: 5432, user: "monitor", database: "billing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := DriverConfig(tt.dsn)
+			if err != nil {
+				t.Fatalf("DriverConfig() error = %v", err)
+			}
+			if cfg.Host != tt.host || cfg.Port != tt.port || cfg.User != tt.user || cfg.Database != tt.database {
+				t.Fatalf("DriverConfig() = %s:%d %s/%s, want %s:%d %s/%s",
+					cfg.Host, cfg.Port, cfg.User, cfg.Database, tt.host, tt.port, tt.user, tt.database)
+			}
+		})
+	}
+
+	t.Run("PGSERVICE", func(t *testing.T) {
+		t.Setenv("PGSERVICE", "orders")
+		cfg, err := DriverConfig("sslmode=disable")
+		if err != nil {
+			t.Fatalf("DriverConfig() error = %v", err)
+		}
+		if cfg.Host != "orders.example.com" || cfg.Database != "orders" {
+			t.Fatalf("DriverConfig() = %s/%s, want the orders service", cfg.Host, cfg.Database)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := DriverConfig("service=reports"); err == nil {
+			t.Fatal("DriverConfig() error = nil, want the service not to be found")
+		}
+	})
+}
+
+func TestReadServiceFileNested(t *testing.T) {
+	path := writeServiceFile(t, t.TempDir(), "pg_service.conf", "[orders]\nservice=billing\n")
+	if _, err := readServiceFile(path, "orders"); err == nil {
+		t.Fatal("readServiceFile() error = nil, want nested services to be rejected")
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TLSHandshake is a TLS handshake of the driver with one of the hosts of a
+// DSN.
+type TLSHandshake struct {
+	// Server is the host and port of the server, as given in the DSN.
+	Server string
+	// Certificates is the chain presented by the server. It is empty when
+	// the handshake failed before the server presented one.
+	Certificates []*x509.Certificate
+	// Err is why the handshake failed. A certificate that does not pass
+	// verification for the sslmode is reported as a
+	// *tls.CertificateVerificationError, a server that does not support
+	// TLS as pq.ErrSSLNotSupported.
+	Err error
+}
+
+// OpenDBObservingTLS is like OpenDB, and calls observe after every TLS
+// handshake the driver performs while connecting. Network errors and errors
+// returned by the server are not observed, they are returned by the
+// connection as usual.
+//
+// The driver is given a TLS configuration that verifies the server
+// certificate the way the sslmode of the DSN asks for. DSNs that do not use
+// TLS, or use a custom TLS configuration, are connected to as by OpenDB.
+func OpenDBObservingTLS(dsn string, files CredentialFiles, observe func(TLSHandshake)) (*sql.DB, error) {
+	if files.IsZero() {
+		return sql.OpenDB(&dsnConnector{dsn: dsn, observe: observe}), nil
+	}
+	parsed, err := dsnFromString(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&credentialFilesConnector{dsn: parsed, files: files, observe: observe}), nil
+}
+
+// TLSServers returns the hosts and ports of dsn that are connected to over
+// TCP, in the form TLSHandshake.Server reports them.
+func TLSServers(dsn string) ([]string, error) {
+	cfg, err := DriverConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	var servers []string
+	for _, server := range tlsServers(cfg) {
+		if !slices.Contains(servers, server) {
+			servers = append(servers, server)
+		}
+	}
+	slices.Sort(servers)
+	return servers, nil
+}
+
+// tlsServers maps the addresses the driver dials for the hosts of cfg to the
+// host and port given in the DSN. Unix domain sockets never use TLS.
+func tlsServers(cfg pq.Config) map[string]string {
+	type host struct {
+		name string
+		addr string
+		port uint16
+	}
+	hosts := []host{{cfg.Host, "", cfg.Port}}
+	if cfg.Hostaddr.IsValid() {
+		hosts[0].addr = cfg.Hostaddr.String()
+	}
+	for _, m := range cfg.Multi {
+		h := host{m.Host, "", m.Port}
+		if m.Hostaddr.IsValid() {
+			h.addr = m.Hostaddr.String()
+		}
+		hosts = append(hosts, h)
+	}
+
+	servers := make(map[string]string, len(hosts))
+	for _, h := range hosts {
+		port := strconv.Itoa(int(h.port))
+		if h.addr == "" {
+			if filepath.IsAbs(h.name) || strings.HasPrefix(h.name, "@") {
+				continue
+			}
+			h.addr = h.name
+		}
+		name := h.name
+		if name == "" {
+			name = h.addr
+		}
+		servers[net.JoinHostPort(h.addr, port)] = net.JoinHostPort(name, port)
+	}
+	return servers
+}
+
+// tlsConfigKeys numbers the TLS configurations registered with the driver,
+// each connection attempt registers its own.
+var tlsConfigKeys atomic.Uint64
+
+// connectObservingTLS connects with cfg and a TLS configuration registered for
+// this connection only, whose VerifyConnection hook reports the certificates
+// of every host the driver performs the handshake with.
+func connectObservingTLS(ctx context.Context, cfg pq.Config, observe func(TLSHandshake)) (driver.Conn, error) {
+	switch cfg.SSLMode {
+	case "", pq.SSLModePrefer, pq.SSLModeRequire, pq.SSLModeVerifyCA, pq.SSLModeVerifyFull:
+	default:
+		return connectConfig(ctx, cfg)
+	}
+
+	// The driver does not verify the chain under a custom TLS configuration,
+	// so the hook repeats what it does for the sslmode, with the root
+	// certificate resolved the same way. TestObservingTLSLikeDriver keeps
+	// the two in step.
+	custom := cfg
+	roots, verify, err := tlsRoots(&custom)
+	if err != nil {
+		return nil, err
+	}
+	o := &tlsObserver{
+		servers:    tlsServers(cfg),
+		roots:      roots,
+		verify:     verify,
+		verifyHost: cfg.SSLMode == pq.SSLModeVerifyFull,
+		observe:    observe,
+	}
+
+	key := fmt.Sprintf("postgres_exporter-%d", tlsConfigKeys.Add(1))
+	if err := pq.RegisterTLSConfig(key, &tls.Config{
+		// The chain is verified by VerifyConnection, which knows the host
+		// and the sslmode.
+		InsecureSkipVerify: true,
+		VerifyConnection:   o.verifyConnection,
+	}); err != nil {
+		return nil, err
+	}
+	defer pq.RegisterTLSConfig(key, nil)
+
+	custom.SSLMode = pq.SSLMode("pqgo-" + key)
+	connector, err := pq.NewConnectorConfig(custom)
+	if err != nil {
+		return nil, err
+	}
+	connector.Dialer(o)
+
+	conn, err := connector.Connect(ctx)
+	if err == nil {
+		return conn, nil
+	}
+	server, observed, failed := o.state()
+	if cfg.SSLMode == pq.SSLModePrefer && (failed || isTLSError(err)) {
+		// prefer falls back to a connection without TLS, leave that to
+		// the driver.
+		return connectConfig(ctx, cfg)
+	}
+	if !observed && isTLSError(err) {
+		o.observe(TLSHandshake{Server: server, Err: err})
+	}
+	return nil, err
+}
+
+// tlsObserver is the dialer and the VerifyConnection hook of one connection.
+// The driver tries the hosts one after another, so server is the host of the
+// handshake in progress. The driver calls both from the connecting goroutine,
+// mu guards against a dialer or handshake it runs on another one.
+type tlsObserver struct {
+	servers    map[string]string
+	roots      *x509.CertPool
+	verify     bool
+	verifyHost bool
+	observe    func(TLSHandshake)
+
+	mu       sync.Mutex
+	server   string
+	observed bool
+	failed   bool
+}
+
+// state returns the host of the last dial, whether its handshake was
+// observed, and whether any handshake failed verification.
+func (o *tlsObserver) state() (server string, observed, failed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.server, o.observed, o.failed
+}
+
+func (o *tlsObserver) Dial(network, address string) (net.Conn, error) {
+	return o.DialContext(context.Background(), network, address)
+}
+
+func (o *tlsObserver) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return o.DialContext(ctx, network, address)
+}
+
+func (o *tlsObserver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	server, ok := o.servers[address]
+	if !ok {
+		server = address
+	}
+	o.mu.Lock()
+	o.server, o.observed = server, false
+	o.mu.Unlock()
+
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+func (o *tlsObserver) verifyConnection(state tls.ConnectionState) error {
+	o.mu.Lock()
+	server := o.server
+	o.mu.Unlock()
+
+	var err error
+	if o.verify {
+		host := ""
+		if o.verifyHost {
+			host, _, _ = net.SplitHostPort(server)
+		}
+		err = verifyTLSCertificates(state.PeerCertificates, o.roots, host)
+	}
+
+	o.mu.Lock()
+	o.observed = true
+	o.failed = o.failed || err != nil
+	o.mu.Unlock()
+	o.observe(TLSHandshake{Server: server, Certificates: state.PeerCertificates, Err: err})
+	return err
+}
+
+// verifyTLSCertificates verifies the chain presented by the server against
+// roots, and the hostname if it is not empty.
+func verifyTLSCertificates(certs []*x509.Certificate, roots *x509.CertPool, hostname string) error {
+	if len(certs) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		DNSName:       hostname,
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return &tls.CertificateVerificationError{UnverifiedCertificates: certs, Err: err}
+	}
+	return nil
+}
+
+// tlsRoots resolves cfg.SSLRootCert the way the driver does, and returns the
+// CAs to verify the server certificate against and whether the sslmode asks
+// for verification at all. A nil pool uses the system roots.
+func tlsRoots(cfg *pq.Config) (*x509.CertPool, bool, error) {
+	// ~/.postgresql/root.crt is only a default when the sslmode is explicit.
+	if cfg.SSLMode != "" && !cfg.SSLInline && cfg.SSLRootCert == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			f := filepath.Join(home, ".postgresql", "root.crt")
+			if _, err := os.Stat(f); err == nil {
+				cfg.SSLRootCert = f
+			}
+		}
+	}
+
+	verify := true
+	switch cfg.SSLMode {
+	case "", pq.SSLModePrefer, pq.SSLModeRequire:
+		// These verify the chain when a root certificate is available,
+		// but not against the system roots.
+		verify = false
+		if cfg.SSLRootCert != "" {
+			if _, err := os.Stat(cfg.SSLRootCert); err == nil || cfg.SSLInline {
+				verify = true
+			} else if cfg.SSLRootCert != "system" {
+				cfg.SSLRootCert = ""
+			}
+		}
+	}
+	if cfg.SSLRootCert == "" || cfg.SSLRootCert == "system" {
+		return nil, verify, nil
+	}
+
+	rootPEM := []byte(cfg.SSLRootCert)
+	if !cfg.SSLInline {
+		var err error
+		rootPEM, err = os.ReadFile(cfg.SSLRootCert)
+		if err != nil {
+			return nil, false, fmt.Errorf("reading sslrootcert: %w", err)
+		}
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(rootPEM) {
+		return nil, false, errors.New("sslrootcert contains no PEM encoded certificates")
+	}
+	return pool, verify, nil
+}
+
+// isTLSError reports whether err is the TLS handshake failing, as opposed to
+// a network error or the server rejecting the connection.
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, pq.ErrSSLNotSupported),
+		errors.As(err, &recordErr),
+		errors.As(err, &alertErr),
+		errors.As(err, &verifyErr):
+		return true
+	case errors.As(err, &opErr):
+		// An alert sent by the server.
+		return opErr.Op == "remote error"
+	}
+	return false
+}
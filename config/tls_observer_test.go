@@ -0,0 +1,348 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// fakeTLSServer accepts connections speaking just enough of the PostgreSQL
+// protocol to negotiate TLS, with the self-signed certificate it returns, or
+// refuse it. The startup message is answered with an error.
+func fakeTLSServer(t *testing.T, ssl bool) (string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "db.example.com"},
+		DNSNames:              []string{"db.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConf := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				for {
+					var header [8]byte
+					if _, err := io.ReadFull(conn, header[:]); err != nil {
+						return
+					}
+					length := binary.BigEndian.Uint32(header[0:4])
+					if binary.BigEndian.Uint32(header[4:8]) != 80877103 {
+						if _, err := io.CopyN(io.Discard, conn, int64(length-8)); err != nil {
+							return
+						}
+						fields := "SFATAL\x00VFATAL\x00C3D000\x00Mdatabase does not exist\x00\x00"
+						msg := []byte{'E', 0, 0, 0, 0}
+						binary.BigEndian.PutUint32(msg[1:], uint32(4+len(fields)))
+						conn.Write(append(msg, fields...))
+						return
+					}
+					if !ssl {
+						conn.Write([]byte("N"))
+						continue
+					}
+					conn.Write([]byte("S"))
+					server := tls.Server(conn, tlsConf)
+					if err := server.Handshake(); err != nil {
+						return
+					}
+					conn = server
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String(), cert
+}
+
+func writeRootCert(t *testing.T, cert *x509.Certificate) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "root.crt")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pingObservingTLS connects to dsn and returns the observed handshakes and the
+// connection error.
+func pingObservingTLS(t *testing.T, dsn string) ([]TLSHandshake, error) {
+	t.Helper()
+	var mu sync.Mutex
+	var handshakes []TLSHandshake
+	db, err := OpenDBObservingTLS(dsn, CredentialFiles{}, func(h TLSHandshake) {
+		mu.Lock()
+		defer mu.Unlock()
+		handshakes = append(handshakes, h)
+	})
+	if err != nil {
+		t.Fatalf("OpenDBObservingTLS() error = %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = db.PingContext(ctx)
+	mu.Lock()
+	defer mu.Unlock()
+	return handshakes, err
+}
+
+func TestOpenDBObservingTLS(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	addr, cert := fakeTLSServer(t, true)
+	host, port, _ := net.SplitHostPort(addr)
+	rootCert := writeRootCert(t, cert)
+
+	for _, tc := range []struct {
+		name    string
+		dsn     string
+		wantErr func(error) bool
+	}{
+		{
+			name:    "require",
+			dsn:     fmt.Sprintf("host=%s port=%s sslmode=require", host, port),
+			wantErr: func(err error) bool { return err == nil },
+		},
+		{
+			name:    "verify-ca",
+			dsn:     fmt.Sprintf("host=%s port=%s sslmode=verify-ca sslrootcert=%s", host, port, rootCert),
+			wantErr: func(err error) bool { return err == nil },
+		},
+		{
+			// The certificate is trusted, but not issued for the address dialled.
+			name: "verify-full",
+			dsn:  fmt.Sprintf("host=%s port=%s sslmode=verify-full sslrootcert=%s", host, port, rootCert),
+			wantErr: func(err error) bool {
+				var verifyErr *tls.CertificateVerificationError
+				var hostnameErr x509.HostnameError
+				return errors.As(err, &verifyErr) && errors.As(err, &hostnameErr)
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handshakes, err := pingObservingTLS(t, tc.dsn)
+			if err == nil {
+				t.Fatal("Ping() error = nil, want the connection to fail")
+			}
+			if len(handshakes) != 1 {
+				t.Fatalf("observed %d handshakes, want 1", len(handshakes))
+			}
+			h := handshakes[0]
+			if h.Server != addr {
+				t.Errorf("Server = %q, want %q", h.Server, addr)
+			}
+			if len(h.Certificates) != 1 || !h.Certificates[0].Equal(cert) {
+				t.Errorf("Certificates = %v, want the server certificate", h.Certificates)
+			}
+			if !tc.wantErr(h.Err) {
+				t.Errorf("Err = %v", h.Err)
+			}
+		})
+	}
+}
+
+// TestObservingTLSLikeDriver connects with every sslmode and source of root
+// certificate both through the observer and the driver alone, which must
+// agree on whether the server is reached.
+func TestObservingTLSLikeDriver(t *testing.T) {
+	addr, cert := fakeTLSServer(t, true)
+	_, other := fakeTLSServer(t, true)
+	host, port, _ := net.SplitHostPort(addr)
+	trusted := writeRootCert(t, cert)
+	untrusted := writeRootCert(t, other)
+
+	hosts := map[string]string{
+		// The certificate is issued for db.example.com only.
+		"address":  fmt.Sprintf("host=%s port=%s", host, port),
+		"hostname": fmt.Sprintf("host=db.example.com hostaddr=%s port=%s", host, port),
+	}
+	rootCerts := []struct {
+		name     string
+		param    string
+		homeCert string
+	}{
+		{name: "none"},
+		{name: "trusted", param: "sslrootcert=" + trusted},
+		{name: "untrusted", param: "sslrootcert=" + untrusted},
+		{name: "missing", param: "sslrootcert=" + filepath.Join(t.TempDir(), "root.crt")},
+		{name: "system", param: "sslrootcert=system"},
+		{name: "home trusted", homeCert: trusted},
+		{name: "home untrusted", homeCert: untrusted},
+		{name: "home and untrusted", param: "sslrootcert=" + untrusted, homeCert: trusted},
+	}
+	reached := func(err error) bool {
+		var pqErr *pq.Error
+		return errors.As(err, &pqErr) && pqErr.Code == "3D000"
+	}
+
+	for _, mode := range []string{"", "prefer", "require", "verify-ca", "verify-full"} {
+		for hostName, hostParams := range hosts {
+			for _, rc := range rootCerts {
+				dsn, modeName := hostParams+" connect_timeout=5 "+rc.param, "default"
+				if mode != "" {
+					dsn, modeName = dsn+" sslmode="+mode, mode
+				}
+				t.Run(fmt.Sprintf("%s/%s/%s", modeName, hostName, rc.name), func(t *testing.T) {
+					home := t.TempDir()
+					t.Setenv("HOME", home)
+					for _, env := range []string{"PGSSLMODE", "PGSSLROOTCERT"} {
+						t.Setenv(env, "")
+						os.Unsetenv(env)
+					}
+					if rc.homeCert != "" {
+						pemBytes, err := os.ReadFile(rc.homeCert)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if err := os.MkdirAll(filepath.Join(home, ".postgresql"), 0o700); err != nil {
+							t.Fatal(err)
+						}
+						if err := os.WriteFile(filepath.Join(home, ".postgresql", "root.crt"), pemBytes, 0o600); err != nil {
+							t.Fatal(err)
+						}
+					}
+
+					var driverErr error
+					connector, err := pq.NewConnector(dsn)
+					if err == nil {
+						db := sql.OpenDB(connector)
+						ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+						driverErr = db.PingContext(ctx)
+						cancel()
+						db.Close()
+					}
+					handshakes, observedErr := pingObservingTLS(t, dsn)
+
+					if err != nil {
+						if reached(observedErr) {
+							t.Fatalf("observer reached the server, driver rejected the DSN: %v", err)
+						}
+						return
+					}
+					if reached(driverErr) != reached(observedErr) {
+						t.Fatalf("driver error = %v, observer error = %v", driverErr, observedErr)
+					}
+					if mode == "prefer" {
+						return
+					}
+					// Without a fallback, the handshake is observed as
+					// failed exactly when the connection fails before the
+					// server is reached.
+					if reached(observedErr) && (len(handshakes) != 1 || handshakes[0].Err != nil) {
+						t.Fatalf("handshakes = %+v, want one that succeeded", handshakes)
+					}
+					if !reached(observedErr) && len(handshakes) > 0 && handshakes[len(handshakes)-1].Err == nil {
+						t.Fatalf("handshakes = %+v, want the last one to fail", handshakes)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestOpenDBObservingTLSSSLNotSupported(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	addr, _ := fakeTLSServer(t, false)
+	host, port, _ := net.SplitHostPort(addr)
+
+	handshakes, err := pingObservingTLS(t, fmt.Sprintf("host=%s port=%s sslmode=require", host, port))
+	if !errors.Is(err, pq.ErrSSLNotSupported) {
+		t.Fatalf("Ping() error = %v, want %v", err, pq.ErrSSLNotSupported)
+	}
+	if len(handshakes) != 1 || handshakes[0].Server != addr || !errors.Is(handshakes[0].Err, pq.ErrSSLNotSupported) {
+		t.Fatalf("handshakes = %+v, want one refused by %s", handshakes, addr)
+	}
+
+	// prefer falls back to a connection without TLS, which the server
+	// answers.
+	handshakes, err = pingObservingTLS(t, fmt.Sprintf("host=%s port=%s sslmode=prefer", host, port))
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "3D000" {
+		t.Fatalf("Ping() error = %v, want the server error", err)
+	}
+	if len(handshakes) != 0 {
+		t.Fatalf("handshakes = %+v, want none", handshakes)
+	}
+}
+
+func TestOpenDBObservingTLSNetworkError(t *testing.T) {
+	handshakes, err := pingObservingTLS(t, "host=127.0.0.1 port=1 sslmode=require connect_timeout=5")
+	if err == nil {
+		t.Fatal("Ping() error = nil, want the connection to fail")
+	}
+	if len(handshakes) != 0 {
+		t.Fatalf("handshakes = %+v, want network errors not to be observed", handshakes)
+	}
+}
+
+func TestTLSServers(t *testing.T) {
+	got, err := TLSServers("host=db2,db1,/var/run/postgresql,db1 port=5433,5432,5432,5432")
+	if err != nil {
+		t.Fatalf("TLSServers() error = %v", err)
+	}
+	want := []string{"db1:5432", "db2:5433"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("TLSServers() = %v, want %v", got, want)
+	}
+}
@@ -14,6 +14,7 @@
 package exporter
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -21,6 +22,7 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/prometheus-community/postgres_exporter/config"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -127,10 +129,12 @@ func GetDataSources() ([]string, error) {
 
 	var user, pass, uri string
 
+	// The user and password files are read again whenever a connection is
+	// opened, so one that is not mounted yet only fails the connections.
 	dataSourceUserFile := os.Getenv("DATA_SOURCE_USER_FILE")
 	if len(dataSourceUserFile) != 0 {
 		fileContents, err := os.ReadFile(dataSourceUserFile)
-		if err != nil {
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return nil, fmt.Errorf("failed loading data source user file %s: %s", dataSourceUserFile, err.Error())
 		}
 		user = strings.TrimSpace(string(fileContents))
@@ -141,7 +145,7 @@ func GetDataSources() ([]string, error) {
 	dataSourcePassFile := os.Getenv("DATA_SOURCE_PASS_FILE")
 	if len(dataSourcePassFile) != 0 {
 		fileContents, err := os.ReadFile(dataSourcePassFile)
-		if err != nil {
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return nil, fmt.Errorf("failed loading data source pass file %s: %s", dataSourcePassFile, err.Error())
 		}
 		pass = strings.TrimSpace(string(fileContents))
@@ -171,3 +175,17 @@ func GetDataSources() ([]string, error) {
 
 	return []string{dsn}, nil
 }
+
+// GetDataSourceCredentialFiles returns the DATA_SOURCE_{USER|PASS}_FILE files
+// that apply to the data sources from GetDataSources. Reading them on every
+// connection, rather than only once at startup, lets rotated secrets take
+// effect. They do not apply when DATA_SOURCE_NAME is set.
+func GetDataSourceCredentialFiles() config.CredentialFiles {
+	if os.Getenv("DATA_SOURCE_NAME") != "" {
+		return config.CredentialFiles{}
+	}
+	return config.CredentialFiles{
+		UsernameFile: os.Getenv("DATA_SOURCE_USER_FILE"),
+		PasswordFile: os.Getenv("DATA_SOURCE_PASS_FILE"),
+	}
+}
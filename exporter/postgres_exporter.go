@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -347,8 +348,9 @@ type Exporter struct {
 	// servers contains metrics map and query overrides.
 	servers *Servers
 
-	logger       *slog.Logger
-	metricPrefix string
+	logger          *slog.Logger
+	metricPrefix    string
+	credentialFiles config.CredentialFiles
 }
 
 // ExporterOpt configures Exporter.
@@ -405,6 +407,13 @@ func WithMetricPrefix(prefix string) ExporterOpt {
 	}
 }
 
+// WithCredentialFiles configures files to read the username and password from.
+func WithCredentialFiles(files config.CredentialFiles) ExporterOpt {
+	return func(e *Exporter) {
+		e.credentialFiles = files
+	}
+}
+
 func parseConstLabels(s string, logger *slog.Logger) prometheus.Labels {
 	labels := make(prometheus.Labels)
 
@@ -444,7 +453,7 @@ func NewExporter(dsn []string, logger *slog.Logger, opts ...ExporterOpt) *Export
 	}
 
 	e.setupInternalMetrics()
-	e.servers = NewServers(ServerWithLabels(e.constantLabels), ServerWithLogger(e.logger))
+	e.servers = NewServers(ServerWithLabels(e.constantLabels), ServerWithLogger(e.logger), ServerWithCredentialFiles(e.credentialFiles))
 
 	return e
 }
@@ -18,6 +18,7 @@ package exporter
 import (
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -254,6 +255,33 @@ func (s *FunctionalSuite) TestParseFingerprint(c *C) {
 	}
 }
 
+// Targets naming services get the host and port of their service, so they
+// are told apart.
+func (s *FunctionalSuite) TestParseFingerprintService(c *C) {
+	serviceFile := filepath.Join(c.MkDir(), "pg_service.conf")
+	err := os.WriteFile(serviceFile, []byte("[orders]\nhost=orders.example.com\nport=6432\n\n[billing]\nhost=billing.example.com\n"), 0o600)
+	c.Assert(err, IsNil)
+
+	err = os.Setenv("PGSERVICEFILE", serviceFile)
+	c.Assert(err, IsNil)
+	defer UnsetEnvironment(c, "PGSERVICEFILE")
+
+	cases := []struct {
+		url         string
+		fingerprint string
+	}{
+		{url: "service=orders", fingerprint: "orders.example.com:6432"},
+		{url: "postgresql://?service=billing", fingerprint: "billing.example.com:5432"},
+		{url: "service=orders port=5433", fingerprint: "orders.example.com:5433"},
+	}
+
+	for _, cs := range cases {
+		f, err := parseFingerprint(cs.url)
+		c.Assert(err, IsNil)
+		c.Assert(f, Equals, cs.fingerprint)
+	}
+}
+
 func (s *FunctionalSuite) TestParseConstLabels(c *C) {
 	cases := []struct {
 		s      string
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/postgres_exporter/config"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/promslog"
 )
@@ -45,6 +46,8 @@ type Server struct {
 	metricCache map[string]cachedMetrics
 	cacheMtx    sync.Mutex
 	logger      *slog.Logger
+
+	credentialFiles config.CredentialFiles
 }
 
 // ServerOpt configures a server.
@@ -65,6 +68,14 @@ func ServerWithLogger(logger *slog.Logger) ServerOpt {
 	}
 }
 
+// ServerWithCredentialFiles reads the username and password from files on
+// every new connection.
+func ServerWithCredentialFiles(files config.CredentialFiles) ServerOpt {
+	return func(s *Server) {
+		s.credentialFiles = files
+	}
+}
+
 // NewServer establishes a new connection using DSN.
 func NewServer(dsn string, opts ...ServerOpt) (*Server, error) {
 	fingerprint, err := parseFingerprint(dsn)
@@ -72,15 +83,7 @@ func NewServer(dsn string, opts ...ServerOpt) (*Server, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, err
-	}
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
-
 	s := &Server{
-		db:     db,
 		master: false,
 		labels: prometheus.Labels{
 			serverLabelName: fingerprint,
@@ -93,6 +96,14 @@ func NewServer(dsn string, opts ...ServerOpt) (*Server, error) {
 		opt(s)
 	}
 
+	db, err := config.OpenDB(dsn, s.credentialFiles)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	s.db = db
+
 	s.logger.Info("Established new database connection", "fingerprint", fingerprint)
 
 	return s, nil
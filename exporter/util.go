@@ -21,6 +21,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus-community/postgres_exporter/config"
 )
 
 // convert a string to the corresponding ColumnUsage
@@ -161,6 +164,8 @@ func dbToString(t interface{}) (string, bool) {
 }
 
 func parseFingerprint(dsn string) (string, error) {
+	var host, port string
+
 	// Only postgres:// and postgresql:// are valid DSN URL schemes; anything
 	// else (e.g. a key=value DSN like "host=example port=1234") falls through
 	// to the key=value parser below.
@@ -169,47 +174,64 @@ func parseFingerprint(dsn string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("malformed dsn %q", dsn)
 		}
+		host, port = u.Hostname(), u.Port()
+	} else {
+		pairs := strings.Split(dsn, " ")
+		kv := make(map[string]string, len(pairs))
+		for _, pair := range pairs {
+			splitted := strings.SplitN(pair, "=", 2)
+			if len(splitted) != 2 {
+				return "", fmt.Errorf("malformed dsn %q", dsn)
+			}
+			key := strings.Trim(splitted[0], "'\"")
+			value := strings.Trim(splitted[1], "'\"")
+			kv[key] = value
+		}
+		host, port = kv["host"], kv["port"]
+	}
 
-		host := u.Hostname()
+	// What the DSN leaves out comes from its service or the environment,
+	// the way the driver resolves it.
+	if host == "" || port == "" {
+		cfg, err := config.DriverConfig(dsn)
+		if err != nil {
+			return "", err
+		}
+		hosts, ports := driverHostsPorts(cfg)
 		if host == "" {
-			host = "localhost"
+			host = hosts
 		}
-
-		port := u.Port()
 		if port == "" {
-			port = "5432"
+			port = ports
 		}
-
-		return host + ":" + port, nil
 	}
 
-	pairs := strings.Split(dsn, " ")
-	kv := make(map[string]string, len(pairs))
-	for _, pair := range pairs {
-		splitted := strings.SplitN(pair, "=", 2)
-		if len(splitted) != 2 {
-			return "", fmt.Errorf("malformed dsn %q", dsn)
-		}
-		key := strings.Trim(splitted[0], "'\"")
-		value := strings.Trim(splitted[1], "'\"")
-		kv[key] = value
-	}
-
-	var fingerprint string
+	return host + ":" + port, nil
+}
 
-	if host, ok := kv["host"]; ok {
-		fingerprint += host
-	} else {
-		fingerprint += "localhost"
+// driverHostsPorts returns the hosts and the ports the driver connects to,
+// comma separated like in a key=value DSN.
+func driverHostsPorts(cfg pq.Config) (string, string) {
+	host := cfg.Host
+	if host == "" && cfg.Hostaddr.IsValid() {
+		host = cfg.Hostaddr.String()
 	}
-
-	if port, ok := kv["port"]; ok {
-		fingerprint += ":" + port
-	} else {
-		fingerprint += ":5432"
+	hosts := []string{host}
+	ports := []string{strconv.Itoa(int(cfg.Port))}
+	samePort := true
+	for _, m := range cfg.Multi {
+		host := m.Host
+		if host == "" && m.Hostaddr.IsValid() {
+			host = m.Hostaddr.String()
+		}
+		hosts = append(hosts, host)
+		ports = append(ports, strconv.Itoa(int(m.Port)))
+		samePort = samePort && m.Port == cfg.Port
 	}
-
-	return fingerprint, nil
+	if samePort {
+		ports = ports[:1]
+	}
+	return strings.Join(hosts, ","), strings.Join(ports, ",")
 }
 
 func loggableDSN(dsn string) string {
@@ -221,6 +243,11 @@ func loggableDSN(dsn string) string {
 	if pDSN.User != nil {
 		pDSN.User = url.UserPassword(pDSN.User.Username(), "PASSWORD_REMOVED")
 	}
+	// An inline client key is as sensitive as a password
+	if query := pDSN.Query(); query.Get("sslinline") == "true" && query.Has("sslkey") {
+		query.Set("sslkey", "SSLKEY_REMOVED")
+		pDSN.RawQuery = query.Encode()
+	}
 
 	return pDSN.String()
 }